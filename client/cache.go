@@ -15,11 +15,11 @@ import (
 	"github.com/pkg/errors"
 )
 
+// assetCacheKey is the full URL of the asset, without any fragment.
 func assetCacheKey(u *url.URL) string {
-	if u.Host != "whateleyacademy.net" {
-		panic(errors.Errorf("Bad host for asset cache: %s", u.String()))
-	}
-	return u.EscapedPath()
+	k := *u
+	k.Fragment = ""
+	return k.String()
 }
 
 var dbMigrations = []struct {
@@ -67,6 +67,17 @@ var dbMigrations = []struct {
 			return err
 		},
 	},
+	{
+		Version: "2026-10-18-12:40:00",
+		Apply: func(db *sql.DB) error {
+			// Asset cache keys used to be the path on whateleyacademy.net
+			_, err := db.Exec(`
+			UPDATE cachedAssets
+			SET cacheKey = 'http://whateleyacademy.net' || cacheKey
+			WHERE cacheKey LIKE '/%'`)
+			return err
+		},
+	},
 }
 
 var createMigrationsTable = dbMigrations[0]
@@ -252,6 +263,10 @@ func (c *WANetwork) PurgeCache(u StoryURL) (bool, error) {
 
 // returns -1 if no match
 func (c *WANetwork) cacheCheckStory(u StoryURL) (int64, error) {
+	if c.db == nil {
+		// no CacheFile
+		return -1, nil
+	}
 	row := stmtSelectStoryExistsInCache.QueryRow(u.CacheKey())
 	var id int64 = -1
 	var lastUpdated time.Time
@@ -278,6 +293,9 @@ func (c *WANetwork) cacheGetStory(id int64) ([]byte, error) {
 }
 
 func (c *WANetwork) cachePutStory(id int64, u StoryURL, body string) error {
+	if c.db == nil {
+		return nil
+	}
 	var err error
 	if id == -1 {
 		_, err = stmtInsertStoryCacheData.Exec(u.CacheKey(), time.Now().UTC(), body)
@@ -288,6 +306,10 @@ func (c *WANetwork) cachePutStory(id int64, u StoryURL, body string) error {
 }
 
 func (c *WANetwork) cacheCheckAsset(u *url.URL) (int64, error) {
+	if c.db == nil {
+		// no CacheFile
+		return -1, nil
+	}
 	row := stmtSelectAssetExistsInCache.QueryRow(assetCacheKey(u))
 	var id int64 = -1
 	var lastUpdated time.Time
@@ -315,6 +337,9 @@ func (c *WANetwork) cacheGetAsset(id int64) ([]byte, string, error) {
 }
 
 func (c *WANetwork) cachePutAsset(id int64, u *url.URL, body []byte, contentType string) error {
+	if c.db == nil {
+		return nil
+	}
 	var err error
 	if id == -1 {
 		_, err = stmtInsertAssetCacheData.Exec(assetCacheKey(u), time.Now().UTC(), body, contentType)
@@ -0,0 +1,86 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package client
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// Errors for fetch failures. They are wrapped with github.com/pkg/errors; check for them with
+// errors.Is and errors.As, not by comparing messages.
+var (
+	// ErrNotFound is a 404 response: the story was deleted or never existed.
+	ErrNotFound = errors.New("page not found")
+	// ErrForbidden is a 401 or 403 response, e.g. for stories that need a login.
+	ErrForbidden = errors.New("access forbidden")
+	// ErrNotAStory is returned for pages that are not stories, e.g. forum pages. See NotAStoryError.
+	ErrNotAStory = errors.New("not a story")
+	// ErrOffline is returned for requests made in offline mode.
+	ErrOffline = errors.New("offline mode")
+	// ErrRateLimited is a 429 response.
+	ErrRateLimited = errors.New("rate limited")
+)
+
+// HTTPStatusError is a response with a status other than 200. It matches ErrNotFound,
+// ErrForbidden and ErrRateLimited with errors.Is.
+type HTTPStatusError struct {
+	StatusCode int
+	URL        string
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("Non-200 response: %d for %s", e.StatusCode, e.URL)
+}
+
+func (e *HTTPStatusError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden || e.StatusCode == http.StatusUnauthorized
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// NotAStoryError is a URL on the site that is not a story. It matches ErrNotAStory with errors.Is.
+type NotAStoryError struct {
+	URL string
+	// Category is the first part of the path, e.g. "community" for the forum. It is empty for URLs
+	// that are not on the site.
+	Category string
+}
+
+func (e *NotAStoryError) Error() string {
+	return fmt.Sprintf("got %s", e.URL)
+}
+
+func (e *NotAStoryError) Is(target error) bool {
+	return target == ErrNotAStory
+}
+
+// FetchError is a failed download of a story page: a network error, an error response, or a body
+// that could not be read. GetStoryByID returns it for every download failure, so that those can be
+// told apart from cache and parse errors. The cause, e.g. an HTTPStatusError, is still found by
+// errors.Is and errors.As.
+type FetchError struct {
+	URL string
+	Err error
+}
+
+func (e *FetchError) Error() string {
+	return fmt.Sprintf("fetching page HTML: %s", e.Err)
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+func (e *FetchError) Cause() error {
+	return e.Err
+}
@@ -0,0 +1,348 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+// Package fakesite is a stand-in for the Crystal Hall (whateleyacademy.net), for trying out the
+// tools without the network. It serves story pages in the FLEXIcontent / Joomla layout of the real
+// site, images under /images/, and can answer with errors or slowly.
+package fakesite // import "github.com/riking/whateley-ebooks/client/fakesite"
+
+import (
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/riking/whateley-ebooks/client"
+)
+
+// A Story is one article on the site.
+type Story struct {
+	ID   string
+	Slug string
+	// Category is the category slug in the URL. The default is "original-timeline".
+	Category string
+	// CategoryName is the displayed category name.
+	CategoryName string `yaml:"category-name"`
+	Title        string
+	Author       string
+	// Body is the HTML of the story text.
+	Body      string
+	Tags      []string
+	Hits      int64
+	Published time.Time
+	// Status, if set, is sent instead of the page, e.g. 403 for stories that need a login.
+	Status int
+	// Community makes the page a forum page, whose canonical link is not a story URL, like the
+	// pages that the site moved to its forum.
+	Community bool
+	// Delay is waited before answering.
+	Delay time.Duration
+}
+
+// An Asset is a file under /images/.
+type Asset struct {
+	ContentType string `yaml:"content-type"`
+	Body        []byte
+	Status      int
+}
+
+// A Site serves the stories and assets. Use it as an http.Handler, e.g. with httptest.NewServer,
+// and point a client.WANetwork at it with Redirect.
+type Site struct {
+	// BaseURL is used in canonical links. The default is client.DefaultBaseURL, so that the pages
+	// parse like the real ones.
+	BaseURL string
+	// Delay is waited before answering every request.
+	Delay time.Duration
+
+	mu       sync.Mutex
+	stories  map[string]*Story
+	assets   map[string]*Asset
+	tagIDs   map[string]int
+	requests map[string]int
+}
+
+// New returns an empty site.
+func New() *Site {
+	return &Site{
+		stories:  make(map[string]*Story),
+		assets:   make(map[string]*Asset),
+		tagIDs:   make(map[string]int),
+		requests: make(map[string]int),
+	}
+}
+
+// AddStory adds or replaces a story.
+func (s *Site) AddStory(st Story) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st.Category == "" {
+		st.Category = "original-timeline"
+	}
+	if st.CategoryName == "" {
+		st.CategoryName = st.Category
+	}
+	if st.Slug == "" {
+		st.Slug = "story"
+	}
+	for _, t := range st.Tags {
+		if _, ok := s.tagIDs[t]; !ok {
+			// the first tags on the real site are reserved
+			s.tagIDs[t] = len(s.tagIDs) + 2
+		}
+	}
+	s.stories[st.ID] = &st
+}
+
+// AddAsset adds or replaces a file. path starts with /images/.
+func (s *Site) AddAsset(path string, a Asset) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.assets[path] = &a
+}
+
+// Requests returns how often a path was requested, to check that the client cache is used.
+func (s *Site) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+// storyPathRegexp matches the story URLs the client and the site links use.
+var storyPathRegexp = regexp.MustCompile(`\A/(?:index\.php/)?(?:content_page/)?[a-zA-Z0-9_/-]+?/(\d+)-[a-zA-Z0-9_%-]+\z`)
+
+func (s *Site) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests[r.URL.Path]++
+	delay := s.Delay
+	s.mu.Unlock()
+	time.Sleep(delay)
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/images/"):
+		s.serveAsset(w, r)
+	case strings.HasPrefix(r.URL.Path, "/community"):
+		s.servePage(w, communityPageTmpl, pageData{
+			Canonical: s.baseURL() + r.URL.Path,
+			Title:     "Community",
+		})
+	default:
+		m := storyPathRegexp.FindStringSubmatch(r.URL.Path)
+		if m == nil {
+			http.NotFound(w, r)
+			return
+		}
+		s.serveStory(w, r, m[1])
+	}
+}
+
+func (s *Site) baseURL() string {
+	if s.BaseURL == "" {
+		return strings.TrimSuffix(client.DefaultBaseURL, "/")
+	}
+	return strings.TrimSuffix(s.BaseURL, "/")
+}
+
+func (s *Site) serveAsset(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	a, ok := s.assets[r.URL.Path]
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if a.Status != 0 && a.Status != http.StatusOK {
+		http.Error(w, http.StatusText(a.Status), a.Status)
+		return
+	}
+	if a.ContentType != "" {
+		w.Header().Set("Content-Type", a.ContentType)
+	}
+	w.Write(a.Body)
+}
+
+type pageTag struct {
+	ID         int
+	Slug, Name string
+}
+
+type pageData struct {
+	Canonical    string
+	Title        string
+	Author       string
+	Category     string
+	CategoryName string
+	Hits         int64
+	Published    string
+	Tags         []pageTag
+	Body         template.HTML
+}
+
+var tagSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+func (s *Site) serveStory(w http.ResponseWriter, r *http.Request, id string) {
+	s.mu.Lock()
+	st, ok := s.stories[id]
+	var tags []pageTag
+	if ok {
+		for _, t := range st.Tags {
+			slug := strings.Trim(tagSlugChars.ReplaceAllString(strings.ToLower(t), "-"), "-")
+			tags = append(tags, pageTag{ID: s.tagIDs[t], Slug: slug, Name: t})
+		}
+	}
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	time.Sleep(st.Delay)
+	if st.Status != 0 && st.Status != http.StatusOK {
+		http.Error(w, http.StatusText(st.Status), st.Status)
+		return
+	}
+
+	if st.Community {
+		s.servePage(w, communityPageTmpl, pageData{
+			Canonical: fmt.Sprintf("%s/community/forum/%s-%s", s.baseURL(), st.ID, st.Slug),
+			Title:     st.Title,
+		})
+		return
+	}
+	data := pageData{
+		Canonical:    fmt.Sprintf("%s/index.php/%s/%s-%s", s.baseURL(), st.Category, st.ID, url.PathEscape(st.Slug)),
+		Title:        st.Title,
+		Author:       st.Author,
+		Category:     st.Category,
+		CategoryName: st.CategoryName,
+		Hits:         st.Hits,
+		Tags:         tags,
+		Body:         template.HTML(st.Body),
+	}
+	if !st.Published.IsZero() {
+		data.Published = st.Published.Format("Monday, 02 January 2006 15:04")
+	}
+	s.servePage(w, storyPageTmpl, data)
+}
+
+func (s *Site) servePage(w http.ResponseWriter, tmpl *template.Template, data pageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := tmpl.Execute(w, data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// StoryIDs lists the stories of the site.
+func (s *Site) StoryIDs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []string
+	for k := range s.stories {
+		ids = append(ids, k)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Redirect returns a transport for client.Options that sends the requests for the Crystal Hall
+// (see client.IsSiteHost) to the server at target, e.g. "http://127.0.0.1:8080". Other requests
+// are sent as usual.
+func Redirect(target string, parent http.RoundTripper) (http.RoundTripper, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, errors.Errorf("fakesite: bad server address %q", target)
+	}
+	if parent == nil {
+		parent = http.DefaultTransport
+	}
+	return &redirectTransport{target: u, parent: parent}, nil
+}
+
+type redirectTransport struct {
+	target *url.URL
+	parent http.RoundTripper
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !client.IsSiteHost(req.URL.Hostname()) {
+		return t.parent.RoundTrip(req)
+	}
+	r := new(http.Request)
+	*r = *req
+	u := *req.URL
+	u.Scheme = t.target.Scheme
+	u.Host = t.target.Host
+	r.URL = &u
+	r.Host = u.Host
+	return t.parent.RoundTrip(r)
+}
+
+type config struct {
+	Delay   time.Duration
+	Stories []Story
+	Assets  []struct {
+		// Path is the URL path, starting with /images/
+		Path        string
+		File        string
+		ContentType string `yaml:"content-type"`
+		Status      int
+	}
+}
+
+// LoadConfig reads a site from a YAML file with a list of stories and assets. Asset files are
+// relative to the directory of the config file.
+func LoadConfig(filename string) (*Site, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading fakesite config")
+	}
+	var c config
+	err = yaml.Unmarshal(b, &c)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", filename)
+	}
+	s := New()
+	s.Delay = c.Delay
+	for _, st := range c.Stories {
+		if st.ID == "" {
+			return nil, errors.Errorf("%s: story %q has no id", filename, st.Title)
+		}
+		s.AddStory(st)
+	}
+	for _, a := range c.Assets {
+		if !strings.HasPrefix(a.Path, "/images/") {
+			return nil, errors.Errorf("%s: asset path %q does not start with /images/", filename, a.Path)
+		}
+		asset := Asset{ContentType: a.ContentType, Status: a.Status}
+		if a.File != "" {
+			file := a.File
+			if !filepath.IsAbs(file) {
+				file = filepath.Join(filepath.Dir(filename), file)
+			}
+			asset.Body, err = ioutil.ReadFile(file)
+			if err != nil {
+				return nil, errors.Wrapf(err, "reading asset for %s", a.Path)
+			}
+		}
+		if asset.ContentType == "" {
+			asset.ContentType = mime.TypeByExtension(path.Ext(a.Path))
+		}
+		s.AddAsset(a.Path, asset)
+	}
+	return s, nil
+}
@@ -0,0 +1,168 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package fakesite
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/riking/whateley-ebooks/client"
+)
+
+// newClient starts the demo site and returns a client that talks to it, with a fresh cache.
+func newClient(t *testing.T) (*Site, *client.WANetwork, func()) {
+	site := Demo()
+	srv := httptest.NewServer(site)
+	transport, err := Redirect(srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "fakesite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := client.New(client.Options{
+		Transport:  transport,
+		CacheFile:  filepath.Join(dir, "cache.db"),
+		MaxRetries: -1,
+	})
+	return site, c, func() {
+		srv.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	_, c, done := newClient(t)
+	defer done()
+
+	_, err := c.GetStoryByID("3")
+	if !errors.Is(err, client.ErrForbidden) {
+		t.Errorf("#3: expected ErrForbidden, got %v", err)
+	}
+	var status *client.HTTPStatusError
+	if !errors.As(err, &status) || status.StatusCode != 403 {
+		t.Errorf("#3: expected a 403 HTTPStatusError, got %v", err)
+	}
+
+	_, err = c.GetStoryByID("4")
+	var notAStory *client.NotAStoryError
+	if !errors.As(err, &notAStory) || notAStory.Category != "community" {
+		t.Errorf("#4: expected NotAStoryError{Category: community}, got %v", err)
+	}
+	if !errors.Is(err, client.ErrNotAStory) {
+		t.Errorf("#4: expected ErrNotAStory, got %v", err)
+	}
+	var fetchErr *client.FetchError
+	if errors.As(err, &fetchErr) {
+		t.Errorf("#4: a parse error is not a FetchError: %v", err)
+	}
+
+	_, err = c.GetStoryByID("5")
+	if !errors.Is(err, client.ErrNotFound) || errors.Is(err, client.ErrForbidden) {
+		t.Errorf("#5: expected ErrNotFound, got %v", err)
+	}
+	if !errors.As(err, &fetchErr) || fetchErr.URL != "http://whateleyacademy.net/index.php/original-timeline/5-slug" {
+		t.Errorf("#5: expected a FetchError, got %v", err)
+	}
+}
+
+func TestStoryPage(t *testing.T) {
+	site, c, done := newClient(t)
+	defer done()
+
+	page, err := c.GetStoryByID("1")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if page.StoryID != "1" || page.StorySlug != "first-day" || page.CategorySlug != "original-timeline" {
+		t.Errorf("wrong canonical URL: %+v", page.StoryURL)
+	}
+	if page.Title() != "First Day" || page.Authors() != "Demo Author" {
+		t.Errorf("wrong title or author: %q by %q", page.Title(), page.Authors())
+	}
+	tags := page.Tags()
+	if len(tags) != 2 || tags[0].Name != "Demo" || tags[0].Slug != "demo" || tags[1].Name != "Tennyo" || tags[0].ID == "" {
+		t.Errorf("wrong tags: %+v", tags)
+	}
+	published, err := page.PublishDate()
+	if err != nil {
+		t.Errorf("publish date: %v", err)
+	} else if want := time.Date(2016, 7, 4, 10, 0, 0, 0, published.Location()); !published.Equal(want) {
+		t.Errorf("wrong publish date: %s", published)
+	}
+
+	path := "/index.php/original-timeline/1-slug"
+	if n := site.Requests(path); n != 1 {
+		t.Fatalf("%s was requested %d times", path, n)
+	}
+	_, err = c.GetStoryByID("1")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if n := site.Requests(path); n != 1 {
+		t.Errorf("second fetch did not use the cache: %s was requested %d times", path, n)
+	}
+}
+
+// Retries must reach the site again, and stop after MaxRetries.
+func TestRetryUnavailable(t *testing.T) {
+	site := Demo()
+	site.AddStory(Story{ID: "7", Slug: "down", Title: "Down", Status: 503})
+	srv := httptest.NewServer(site)
+	defer srv.Close()
+	transport, err := Redirect(srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := client.New(client.Options{Transport: transport, MaxRetries: 2, RetryDelay: time.Millisecond})
+
+	_, err = c.GetStoryByID("7")
+	var status *client.HTTPStatusError
+	if !errors.As(err, &status) || status.StatusCode != 503 {
+		t.Errorf("expected a 503 error, got %v", err)
+	}
+	if n := site.Requests("/index.php/original-timeline/7-slug"); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+
+	_, err = c.GetStoryByID("5")
+	if n := site.Requests("/index.php/original-timeline/5-slug"); !errors.Is(err, client.ErrNotFound) || n != 1 {
+		t.Errorf("404 was retried: %d requests, %v", n, err)
+	}
+}
+
+// Missing recordings are not retried, even behind another transport.
+func TestReplayNotRetried(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fakesite-replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	transport, err := Redirect("http://127.0.0.1:1", &client.ReplayTransport{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := client.New(client.Options{Transport: transport, MaxRetries: 3, RetryDelay: time.Second})
+
+	start := time.Now()
+	_, err = c.GetStoryByID("1")
+	var notRecorded *client.NotRecordedError
+	if !errors.As(err, &notRecorded) {
+		t.Errorf("expected NotRecordedError, got %v", err)
+	}
+	var fetchErr *client.FetchError
+	if !errors.As(err, &fetchErr) {
+		t.Errorf("a missing recording is not a FetchError: %v", err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("missing recording was retried, took %s", d)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package fakesite
+
+import (
+	"html/template"
+	"time"
+)
+
+// The page layout follows the real site: the header, menus and sidebar are there so that the
+// client's page stripping has something to remove.
+
+var storyPageTmpl = template.Must(template.New("story").Parse(`<!DOCTYPE html>
+<html lang="en-gb" dir="ltr">
+<head>
+<base href="{{.Canonical}}" />
+<meta http-equiv="content-type" content="text/html; charset=utf-8" />
+<meta name="rights" content="All stories copyright their respective authors" />
+<title>{{.Title}}</title>
+<link href="{{.Canonical}}" rel="canonical" />
+<style>body { font-family: serif; }</style>
+<script>var joomla = {};</script>
+</head>
+<body class="site">
+<div class="header"><a class="brand" href="/">Whateley Academy</a>
+<ul class="nav menu"><li><a href="/index.php/original-timeline">Stories</a></li></ul></div>
+<div class="item-page" itemscope itemtype="https://schema.org/Article">
+<div class="page-header"><h2 itemprop="name">{{.Title}}</h2></div>
+<dl class="article-info muted">
+<dd class="createdby" itemprop="author" itemscope itemtype="https://schema.org/Person">Written by <span itemprop="name">{{.Author}}</span></dd>
+<dd class="category-name">Category: <a href="/index.php/{{.Category}}" itemprop="genre">{{.CategoryName}}</a></dd>
+<dd class="hits"><span class="icon-eye-open"></span><meta itemprop="interactionCount" content="UserPageVisits:{{.Hits}}" />Hits: {{.Hits}}</dd>
+</dl>
+{{with .Published}}<div class="flexi element field_published"><span class="flexi label field_published">Published</span><span class="value">{{.}}</span></div>
+{{end}}<div class="flexi element field_tags"><ul class="tags inline">{{range .Tags}}<li class="tag-{{.ID}} tag-list0" itemprop="keywords"><a href="/index.php/component/tags/tag/{{.ID}}-{{.Slug}}" class="label label-info">{{.Name}}</a></li>{{end}}</ul></div>
+<div class="description group"><div class="desc-content field_text">{{.Body}}</div></div>
+<ul class="pager pagenav"><li class="previous"><a href="#" rel="prev">Prev</a></li></ul>
+</div>
+<div class="sidebar"><h3>Latest Stories</h3><ul><li><a href="/">Home</a></li></ul></div>
+<div class="footer"><p>&copy; Whateley Academy</p></div>
+</body>
+</html>
+`))
+
+var communityPageTmpl = template.Must(template.New("community").Parse(`<!DOCTYPE html>
+<html lang="en-gb">
+<head>
+<title>{{.Title}} - Community</title>
+<link href="{{.Canonical}}" rel="canonical" />
+</head>
+<body><div class="kunena"><h1>{{.Title}}</h1><p>Forum post.</p></div></body>
+</html>
+`))
+
+// Demo returns a site with a few stories: normal ones that link to each other, one that needs a
+// login (403), a forum page, and a slow one. Story 5 does not exist (404).
+func Demo() *Site {
+	s := New()
+	published := time.Date(2016, 7, 4, 10, 0, 0, 0, time.UTC)
+	s.AddStory(Story{
+		ID: "1", Slug: "first-day", Title: "First Day", Author: "Demo Author",
+		CategoryName: "Original Timeline", Tags: []string{"Demo", "Tennyo"}, Hits: 1200, Published: published,
+		Body: `<p>It was the <em>first</em> day.</p>
+<p><strong>Chapter 1</strong></p>
+<p>She walked in. <img src="/images/stories/demo.png" alt="demo" /></p>
+<hr />
+<p>Continued in <a href="/index.php/original-timeline/2-second-day">Second Day</a>.</p>`,
+	})
+	s.AddStory(Story{
+		ID: "2", Slug: "second-day", Title: "Second Day", Author: "Other Author",
+		CategoryName: "Original Timeline", Tags: []string{"Demo"}, Hits: 800, Published: published.AddDate(0, 0, 7),
+		Body: `<p>The next day. Back to <a href="http://whateleyacademy.net/index.php/original-timeline/1-first-day">the first day</a>.</p>`,
+	})
+	s.AddStory(Story{ID: "3", Slug: "members-only", Title: "Members Only", Status: 403})
+	s.AddStory(Story{ID: "4", Slug: "forum-thread", Title: "Forum Thread", Community: true})
+	s.AddStory(Story{
+		ID: "6", Slug: "slow-story", Title: "Slow Story", Author: "Demo Author",
+		CategoryName: "Original Timeline", Published: published.AddDate(0, 1, 0), Delay: 3 * time.Second,
+		Body: `<p>This page took a while.</p>`,
+	})
+	s.AddAsset("/images/stories/demo.png", Asset{ContentType: "image/png", Body: demoPNG})
+	return s
+}
+
+// a 1x1 PNG
+var demoPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00\x1f\x15\xc4\x89" +
+	"\x00\x00\x00\rIDATx\xdac\xfc\xcf\xc0P\x0f\x00\x04\x85\x01\x80\x84\xa9\x8c!\x00\x00\x00\x00IEND\xaeB`\x82")
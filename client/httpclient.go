@@ -10,6 +10,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/cookiejar"
+	"net/url"
 	"os"
 	"strings"
 	"time"
@@ -27,6 +28,7 @@ type WANetwork struct {
 	httpClient http.Client
 	options    Options
 	db         *sql.DB
+	baseURL    *url.URL
 
 	rateChan chan struct{}
 }
@@ -38,6 +40,24 @@ type Options struct {
 	MaxConcurrency int
 	// if Offline, cache entries never expire
 	Offline bool
+	// BaseURL is the address of the site, e.g. "https://whateleyacademy.net/" or a mirror, as
+	// returned by ParseBaseURL. If nil, DefaultBaseURL is used.
+	BaseURL *url.URL
+	// AssetHosts lists the hosts that GetAsset may download from, in addition to the site.
+	// If nil, assets may come from any host.
+	AssetHosts []string
+	// Transport sends the HTTP requests, instead of http.DefaultTransport. See RecordingTransport
+	// and ReplayTransport.
+	Transport http.RoundTripper
+	// Doer, if set, is used instead of the built-in http.Client. The headers, rate limit,
+	// offline mode and retries still apply.
+	Doer Doer
+	// MaxRetries is how often a request is tried again after a network error, a timeout or a 5xx
+	// or 429 response. 0 means 3 retries, negative means none.
+	MaxRetries int
+	// RetryDelay is the wait before the first retry. It doubles for each further retry.
+	// Retry-After headers override it. The default is 2 seconds.
+	RetryDelay time.Duration
 }
 
 type printingRoundTripper struct {
@@ -58,6 +78,13 @@ func (p *printingRoundTripper) RoundTrip(req *http.Request) (*http.Response, err
 func New(opts Options) *WANetwork {
 	c := new(WANetwork)
 	c.options = opts
+	if opts.BaseURL != nil {
+		base := *opts.BaseURL
+		if !strings.HasSuffix(base.Path, "/") {
+			base.Path += "/"
+		}
+		c.baseURL = &base
+	}
 	if opts.UserAgent == "" {
 		opts.UserAgent = "Program Name Not Set (+github.com/riking/whateley-ebooks)"
 	}
@@ -71,7 +98,7 @@ func New(opts Options) *WANetwork {
 	c.Headers.Set("User-Agent", opts.UserAgent)
 	c.httpClient.Jar, _ = cookiejar.New(nil)
 	c.httpClient.Timeout = 45 * time.Second
-	c.httpClient.Transport = &printingRoundTripper{parent: c.httpClient.Transport}
+	c.httpClient.Transport = &printingRoundTripper{parent: opts.Transport}
 
 	if opts.CacheFile != "" {
 		conn, err := sql.Open("sqlite3", fmt.Sprintf("file:%s", opts.CacheFile))
@@ -101,20 +128,60 @@ func (c *WANetwork) UserAgent(ua string) {
 	c.Headers.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
 }
 
+// Do sends a request with the client's headers and rate limit. Network errors, timeouts, and 5xx
+// and 429 responses are retried with backoff, see Options.MaxRetries.
 func (c *WANetwork) Do(req *http.Request) (*http.Response, error) {
 	for k := range c.Headers {
 		req.Header.Set(k, c.Headers.Get(k))
 	}
 	if c.options.Offline {
-		return nil, errors.Errorf("Offline mode; cannot request %s", req.URL.String())
+		return nil, errors.Wrapf(ErrOffline, "cannot request %s", req.URL.String())
+	}
+	for attempt := 0; ; attempt++ {
+		<-c.rateChan
+		var resp *http.Response
+		var err error
+		if c.options.Doer != nil {
+			resp, err = c.options.Doer.Do(req)
+		} else {
+			resp, err = c.httpClient.Do(req)
+		}
+		wait, reason, retry := c.retryWait(attempt, resp, err)
+		if !retry || !rewindBody(req) {
+			return resp, err
+		}
+		if resp != nil {
+			discard(resp)
+		}
+		c.logRetry(req, reason, wait, attempt)
+		time.Sleep(wait)
+	}
+}
+
+// AssetHostAllowed reports whether GetAsset may download from the given host.
+func (c *WANetwork) AssetHostAllowed(host string) bool {
+	host = strings.ToLower(host)
+	if c.IsSiteHost(host) || c.options.AssetHosts == nil {
+		return true
+	}
+	for _, v := range c.options.AssetHosts {
+		if strings.ToLower(strings.TrimSpace(v)) == host {
+			return true
+		}
 	}
-	<-c.rateChan
-	return c.httpClient.Do(req)
+	return false
 }
 
 // GetAsset returns the bytes of the asset, its Content-Type, and any error.
+// Assets from hosts not allowed by Options.AssetHosts are refused with an error.
 func (c *WANetwork) GetAsset(req *http.Request) ([]byte, string, error) {
 	u := req.URL
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, "", errors.Errorf("cannot download asset %s: unsupported scheme", u.String())
+	}
+	if !c.AssetHostAllowed(u.Hostname()) {
+		return nil, "", errors.Errorf("cannot download asset %s: host %s is not in the allowed asset hosts", u.String(), u.Hostname())
+	}
 
 	dbID, err := c.cacheCheckAsset(u)
 	if err != nil && err != errExpired {
@@ -132,6 +199,11 @@ func (c *WANetwork) GetAsset(req *http.Request) ([]byte, string, error) {
 	if err != nil {
 		return nil, "", err
 	}
+	if resp.StatusCode != 200 {
+		// error pages must not end up in the cache, or in a book
+		resp.Body.Close()
+		return nil, "", errors.WithStack(&HTTPStatusError{StatusCode: resp.StatusCode, URL: u.String()})
+	}
 
 	b, err := ioutil.ReadAll(resp.Body)
 	resp.Body.Close()
@@ -159,7 +231,8 @@ func (c *WANetwork) Document(req *http.Request) (*goquery.Document, error) {
 		return nil, err
 	}
 	if resp.StatusCode != 200 {
-		return nil, errors.Errorf("Non-200 response: %d for %s", resp.StatusCode, req.URL.String())
+		resp.Body.Close()
+		return nil, errors.WithStack(&HTTPStatusError{StatusCode: resp.StatusCode, URL: req.URL.String()})
 	}
 	b, err := ioutil.ReadAll(resp.Body)
 	resp.Body.Close()
@@ -174,7 +247,7 @@ func (c *WANetwork) GetStoryByID(storyId string) (*WhateleyPage, error) {
 		storyId = storyId[len("story-"):]
 	}
 
-	u := StoryURL{StoryID: storyId, StorySlug: "slug", CategorySlug: "original-timeline"}
+	u := StoryURL{StoryID: storyId, StorySlug: "slug", CategorySlug: "original-timeline", base: c.baseURL}
 	var doc *goquery.Document
 	fromCache := false
 
@@ -188,6 +261,9 @@ func (c *WANetwork) GetStoryByID(storyId string) (*WhateleyPage, error) {
 			return nil, errors.Wrap(err, "Retrieving value from cache")
 		}
 		doc, err = goquery.NewDocumentFromReader(bytes.NewBuffer(b))
+		if err != nil {
+			return nil, errors.Wrap(err, "reading cached page HTML")
+		}
 		fromCache = true
 	} else {
 		var req *http.Request
@@ -197,13 +273,12 @@ func (c *WANetwork) GetStoryByID(storyId string) (*WhateleyPage, error) {
 		}
 
 		doc, err = c.Document(req)
+		if err != nil {
+			return nil, errors.WithStack(&FetchError{URL: u.URL(), Err: err})
+		}
 	}
 
-	if err != nil {
-		return nil, errors.Wrap(err, "fetching page HTML")
-	}
-
-	page, err := ParseStoryPage(doc)
+	page, err := parseStoryPage(doc, c.baseURL)
 	if err != nil {
 		return nil, errors.Wrap(err, "parsing story page")
 	}
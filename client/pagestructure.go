@@ -6,6 +6,7 @@ package client // import "github.com/riking/whateley-ebooks/client"
 import (
 	"fmt"
 	"html/template"
+	"net/url"
 	"os"
 	"regexp"
 	"strings"
@@ -53,10 +54,14 @@ type StoryURL struct {
 	CategorySlug string
 	StoryID      string
 	StorySlug    string
+
+	// base is the site the URL was parsed for, or nil for the Crystal Hall
+	base *url.URL
 }
 
+// URL returns the address of the story on its site. The site redirects to the right category.
 func (u *StoryURL) URL() string {
-	return fmt.Sprintf("http://whateleyacademy.net/index.php/original-timeline/%s-%s", u.StoryID, u.StorySlug)
+	return siteURL(u.base, fmt.Sprintf("index.php/original-timeline/%s-%s", u.StoryID, u.StorySlug))
 }
 
 func (u *StoryURL) CacheKey() string {
@@ -66,8 +71,9 @@ func (u *StoryURL) CacheKey() string {
 type WhateleyPage struct {
 	StoryURL
 
-	document *goquery.Document
-	tags     []StoryTag
+	document   *goquery.Document
+	tags       []StoryTag
+	contentTOC []ContentTOCLink
 
 	// TODO change to funcs
 	Previous string
@@ -75,7 +81,12 @@ type WhateleyPage struct {
 }
 
 func (p *WhateleyPage) Title() string {
-	return strings.TrimSpace(p.document.Find(`.item-page .page-header h2[itemprop="name"]`).Text())
+	title := strings.TrimSpace(p.document.Find(`.item-page .page-header h2[itemprop="name"]`).Text())
+	if title == "" {
+		// pages cached before the header was kept
+		title = strings.TrimSpace(p.document.Find(`head title`).Text())
+	}
+	return title
 }
 
 func (p *WhateleyPage) Authors() string {
@@ -133,6 +144,33 @@ func (p *WhateleyPage) WordCount() int {
 // NOTE: Must end with a space, so that additional selectors can be concatenated on the end
 const StoryBodySelector = `div.description.group div.desc-content.field_text `
 
+// A ContentTOCLink is one entry of the table of contents the site shows for long stories.
+type ContentTOCLink struct {
+	Title string
+	// Anchor is the id of the element in the story body, without the '#'.
+	Anchor string
+}
+
+// ContentTOC returns the links of the site's table of contents (div.contenttoc) that point into the
+// story. The div itself is removed from the page.
+func (p *WhateleyPage) ContentTOC() []ContentTOCLink {
+	return p.contentTOC
+}
+
+func parseContentTOC(s *goquery.Selection) []ContentTOCLink {
+	var links []ContentTOCLink
+	s.Find("a[href]").Each(func(_ int, a *goquery.Selection) {
+		href, _ := a.Attr("href")
+		idx := strings.IndexByte(href, '#')
+		title := strings.Join(strings.Fields(a.Text()), " ")
+		if idx == -1 || idx == len(href)-1 || title == "" {
+			return
+		}
+		links = append(links, ContentTOCLink{Title: title, Anchor: href[idx+1:]})
+	})
+	return links
+}
+
 func (p *WhateleyPage) StoryBodySelection() *goquery.Selection {
 	return p.document.Find(StoryBodySelector)
 }
@@ -153,19 +191,25 @@ func (p *WhateleyPage) StoryBodyForTemplate() template.HTML {
 	return template.HTML(p.StoryBody())
 }
 
-var canonicalURLRegexp = regexp.MustCompile(`\Ahttp://whateleyacademy\.net/(?:index\.php/)?(?:content_page/)?([a-zA-Z0-9_-]+)/(\d+)-([a-zA-Z0-9_-]+)(?:\?|#|\z)`)
+// canonicalPathRegexp matches the path of a story URL, below the site base URL.
+var canonicalPathRegexp = regexp.MustCompile(`\A/(?:index\.php/)?(?:content_page/)?([a-zA-Z0-9_-]+)/(\d+)-([a-zA-Z0-9_-]+)\z`)
 var idAndSlugRegexp = regexp.MustCompile(`(?:\A|/)(\d+)-([a-zA-Z%0-9-]+)(?:/|\z)`)
 
+// stripExceptionsSelector lists what ParseStoryPage keeps of a page: the metadata read by the
+// WhateleyPage methods, such as the title and author in the page header, and the story body.
 var stripExceptionsSelector = `
 head base,
 meta[name="rights"],
 link[rel="canonical"],
 meta[http-equiv="content-type"],
 head title,
+.item-page .page-header h2[itemprop="name"],
+[itemprop="author"],
 .flexi.element.field_published,
 .flexi.element.field_created,
 .flexi.element.field_modified,
 .flexi.element.field_tags,
+div.contenttoc,
 ` + StoryBodySelector
 
 func nodeInSelection(n *html.Node, s *goquery.Selection) bool {
@@ -186,6 +230,11 @@ func nodeInSelection(n *html.Node, s *goquery.Selection) bool {
 // ParseStoryPage parses a document into a WhateleyPage object.
 // Some processing is performed, e.g. elements not relevant are stripped, and the canonical URL is parsed and stored.
 func ParseStoryPage(doc *goquery.Document) (*WhateleyPage, error) {
+	return parseStoryPage(doc, nil)
+}
+
+// parseStoryPage is ParseStoryPage for a page of the site at base.
+func parseStoryPage(doc *goquery.Document, base *url.URL) (*WhateleyPage, error) {
 	if doc == nil {
 		return nil, errors.Errorf("doc was nil")
 	}
@@ -215,6 +264,7 @@ func ParseStoryPage(doc *goquery.Document) (*WhateleyPage, error) {
 	_ = removed
 
 	page.document.Find("script,style").Remove()
+	page.contentTOC = parseContentTOC(page.document.Find("div.contenttoc"))
 	page.document.Find("a.returnToc,div.contenttoc,div.tocNav").Remove()  // No JS, don't need dynamic TOC
 
 	// Delete the space nodes adjacent to other space nodes
@@ -240,7 +290,7 @@ func ParseStoryPage(doc *goquery.Document) (*WhateleyPage, error) {
 	if !ok {
 		return nil, errors.Errorf("could not find <link rel=canonical>")
 	}
-	urlShort, err := ParseURL(canonicalLink)
+	urlShort, err := parseURL(base, canonicalLink)
 	if err != nil {
 		return nil, err
 	}
@@ -249,14 +299,44 @@ func ParseStoryPage(doc *goquery.Document) (*WhateleyPage, error) {
 	return page, nil
 }
 
-func ParseURL(url string) (StoryURL, error) {
-	m := canonicalURLRegexp.FindStringSubmatch(url)
+// ParseURL parses the URL of a story on the Crystal Hall, over http or https.
+func ParseURL(rawurl string) (StoryURL, error) {
+	return parseURL(nil, rawurl)
+}
+
+// ParseURL parses the URL of a story on the client's site or the Crystal Hall, over http or https.
+func (c *WANetwork) ParseURL(rawurl string) (StoryURL, error) {
+	return parseURL(c.baseURL, rawurl)
+}
+
+func parseURL(base *url.URL, rawurl string) (StoryURL, error) {
+	var m []string
+	notAStory := &NotAStoryError{URL: rawurl}
+	u, err := url.Parse(strings.TrimSpace(rawurl))
+	if err == nil {
+		if p, ok := sitePath(base, u); ok {
+			m = canonicalPathRegexp.FindStringSubmatch(p)
+			notAStory.Category = pathCategory(p)
+		}
+	}
 	if m == nil {
-		return StoryURL{}, errors.Wrap(fmt.Errorf("got %s", url), "Could not parse canonical story URL")
+		return StoryURL{}, errors.Wrap(notAStory, "Could not parse canonical story URL")
 	}
 	return StoryURL{
 		CategorySlug: m[1],
 		StoryID:      m[2],
 		StorySlug:    m[3],
+		base:         base,
 	}, nil
 }
+
+// pathCategory returns the first part of a path on the site, after index.php.
+func pathCategory(p string) string {
+	p = strings.TrimPrefix(p, "/")
+	p = strings.TrimPrefix(p, "index.php/")
+	p = strings.TrimPrefix(p, "content_page/")
+	if i := strings.IndexByte(p, '/'); i != -1 {
+		p = p[:i]
+	}
+	return p
+}
@@ -0,0 +1,66 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package client
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const testStoryPage = `<!DOCTYPE html>
+<html><head>
+<title>Whisper One</title>
+<link href="http://whateleyacademy.net/index.php/original-timeline/34-whisper-one" rel="canonical" />
+</head><body>
+<div class="header"><a href="/">Whateley Academy</a></div>
+<div class="item-page">
+<div class="page-header"><h2 itemprop="name">Whisper One</h2></div>
+<dl class="article-info">
+<dd class="createdby" itemprop="author">Written by <span itemprop="name">Demo Author</span></dd>
+<dd class="hits"><meta itemprop="interactionCount" content="UserPageVisits:1200" />Hits: 1200</dd>
+</dl>
+<div class="description group"><div class="desc-content field_text"><p>Story text.</p></div></div>
+</div>
+<div class="sidebar"><h3>Latest Stories</h3></div>
+</body></html>`
+
+func parseTestPage(t *testing.T, src string) *WhateleyPage {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := ParseStoryPage(doc)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	return page
+}
+
+// The page header is kept when the rest of the page is stripped, as the title and author are
+// shown by the renderers. Pages are cached after stripping, so a page cached without the header
+// still finds its title.
+func TestStrippedPageHeader(t *testing.T) {
+	page := parseTestPage(t, testStoryPage)
+	if page.Title() != "Whisper One" || page.Authors() != "Demo Author" {
+		t.Errorf("wrong title or author: %q by %q", page.Title(), page.Authors())
+	}
+	html, _ := page.Doc().Html()
+	if strings.Contains(html, "Latest Stories") || strings.Contains(html, "Whateley Academy</a>") {
+		t.Errorf("page was not stripped:\n%s", html)
+	}
+
+	// cache round trip
+	page = parseTestPage(t, html)
+	if page.Title() != "Whisper One" || page.Authors() != "Demo Author" {
+		t.Errorf("cached page: wrong title or author: %q by %q", page.Title(), page.Authors())
+	}
+
+	old := strings.Replace(testStoryPage, `<div class="page-header"><h2 itemprop="name">Whisper One</h2></div>`, "", 1)
+	page = parseTestPage(t, old)
+	if page.Title() != "Whisper One" {
+		t.Errorf("page without header: wrong title %q", page.Title())
+	}
+}
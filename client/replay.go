@@ -0,0 +1,128 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Recorded responses are stored one per file, in the HTTP/1.1 wire format, so that they can be
+// read and edited by hand. The file name is made from the method and URL of the request.
+
+var fixtureUnsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// fixtureName returns the file name of the recorded response to a request.
+func fixtureName(req *http.Request) string {
+	u := *req.URL
+	u.Fragment = ""
+	key := req.Method + " " + u.String()
+	sum := sha1.Sum([]byte(key))
+
+	readable := fixtureUnsafeChars.ReplaceAllString(u.Host+u.Path, "_")
+	if len(readable) > 80 {
+		readable = readable[len(readable)-80:]
+	}
+	return strings.ToLower(req.Method) + "-" + strings.Trim(readable, "_") + "-" + hex.EncodeToString(sum[:6]) + ".http"
+}
+
+// A RecordingTransport sends requests through Parent, or http.DefaultTransport, and saves every
+// response to Dir for a ReplayTransport.
+type RecordingTransport struct {
+	Dir    string
+	Parent http.RoundTripper
+}
+
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	parent := t.Parent
+	if parent == nil {
+		parent = http.DefaultTransport
+	}
+	resp, err := parent.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	// DumpResponse puts a copy of the body back into resp
+	b, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		resp.Body.Close()
+		return nil, errors.Wrapf(err, "recording response for %s", req.URL.String())
+	}
+	err = os.MkdirAll(t.Dir, 0755)
+	if err != nil {
+		resp.Body.Close()
+		return nil, errors.Wrap(err, "creating recording directory")
+	}
+	file := filepath.Join(t.Dir, fixtureName(req))
+	tmp, err := ioutil.TempFile(t.Dir, ".recording-")
+	if err == nil {
+		_, err = tmp.Write(b)
+		tmp.Close()
+		if err == nil {
+			err = os.Rename(tmp.Name(), file)
+		}
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}
+	if err != nil {
+		resp.Body.Close()
+		return nil, errors.Wrapf(err, "recording response for %s", req.URL.String())
+	}
+	return resp, nil
+}
+
+// NotRecordedError is returned by a ReplayTransport for a request that was not recorded. It is
+// never retried.
+type NotRecordedError struct {
+	Method, URL string
+	// File is the recording that was expected
+	File string
+}
+
+func (e *NotRecordedError) Error() string {
+	return fmt.Sprintf("replay: no recorded response for %s %s (expected %s)", e.Method, e.URL, e.File)
+}
+
+// A ReplayTransport answers requests with the responses saved by a RecordingTransport, without
+// using the network. Requests that were not recorded fail.
+type ReplayTransport struct {
+	Dir string
+}
+
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	file := filepath.Join(t.Dir, fixtureName(req))
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, errors.WithStack(&NotRecordedError{Method: req.Method, URL: req.URL.String(), File: file})
+	} else if err != nil {
+		return nil, errors.Wrap(err, "replay")
+	}
+	b, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "replay")
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(b)), req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "replay: bad recorded response %s", file)
+	}
+	return resp, nil
+}
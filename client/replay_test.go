@@ -0,0 +1,143 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestFixtureName(t *testing.T) {
+	get := func(u string) *http.Request {
+		req, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+	a := fixtureName(get("http://whateleyacademy.net/index.php/original-timeline/1-slug"))
+	if a != "get-whateleyacademy.net_index.php_original-timeline_1-slug-25e268b7db7a.http" {
+		// the name is the key of checked-in fixtures, it must not change
+		t.Errorf("fixture name changed: %s", a)
+	}
+	if b := fixtureName(get("http://whateleyacademy.net/index.php/original-timeline/1-slug#frag")); b != a {
+		t.Errorf("fragment changed the fixture name: %s != %s", b, a)
+	}
+	if b := fixtureName(get("http://whateleyacademy.net/index.php/original-timeline/1-slug?x=1")); b == a {
+		t.Errorf("query did not change the fixture name")
+	}
+	long := fixtureName(get("http://whateleyacademy.net/" + strings.Repeat("a", 200)))
+	if len(long) > 120 {
+		t.Errorf("fixture name too long: %s", long)
+	}
+}
+
+// Responses recorded from a server must replay the same once the server is gone, including
+// chunked and gzipped bodies.
+func TestRecordReplay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/plain":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("plain body"))
+		case "/chunked":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("first chunk, "))
+			w.(http.Flusher).Flush()
+			w.Write([]byte("second chunk"))
+		case "/gzip":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			gz.Write([]byte("compressed body"))
+			gz.Close()
+		case "/missing":
+			http.NotFound(w, r)
+		}
+	}))
+
+	dir, err := ioutil.TempDir("", "replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	want := map[string]string{
+		"/plain":   "plain body",
+		"/chunked": "first chunk, second chunk",
+		"/gzip":    "compressed body",
+	}
+	fetch := func(c *WANetwork, path string) (string, error) {
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		b, _, err := c.GetAsset(req)
+		return string(b), err
+	}
+
+	recorder := New(Options{Transport: &RecordingTransport{Dir: dir}, MaxRetries: -1})
+	for path, body := range want {
+		got, err := fetch(recorder, path)
+		if err != nil {
+			t.Fatalf("recording %s: %v", path, err)
+		}
+		if got != body {
+			t.Errorf("recording %s: got %q, expected %q", path, got, body)
+		}
+	}
+	if _, err := fetch(recorder, "/missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("recording /missing: expected ErrNotFound, got %v", err)
+	}
+
+	// no network from here on
+	srv.Close()
+
+	player := New(Options{Transport: &ReplayTransport{Dir: dir}, MaxRetries: -1})
+	for path, body := range want {
+		got, err := fetch(player, path)
+		if err != nil {
+			t.Fatalf("replaying %s: %v", path, err)
+		}
+		if got != body {
+			t.Errorf("replaying %s: got %q, expected %q", path, got, body)
+		}
+	}
+	if _, err := fetch(player, "/missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("replaying /missing: expected ErrNotFound, got %v", err)
+	}
+	if _, err := fetch(player, "/never-recorded"); err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("replaying a request that was not recorded: got %v", err)
+	}
+}
+
+// testdata/replay was recorded from fakesite.Demo through a RecordingTransport.
+func TestReplayFixtures(t *testing.T) {
+	c := New(Options{Transport: &ReplayTransport{Dir: "testdata/replay"}, MaxRetries: -1})
+
+	page, err := c.GetStoryByID("1")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if page.StoryID != "1" || page.StorySlug != "first-day" || page.Title() != "First Day" || page.Authors() != "Demo Author" {
+		t.Errorf("wrong story: #%s %s %q by %q", page.StoryID, page.StorySlug, page.Title(), page.Authors())
+	}
+	if !strings.Contains(page.StoryBody(), "first</em> day") {
+		t.Errorf("story body not found:\n%s", page.StoryBody())
+	}
+
+	req, _ := http.NewRequest("GET", "http://whateleyacademy.net/images/stories/demo.png", nil)
+	b, contentType, err := c.GetAsset(req)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if contentType != "image/png" || !bytes.HasPrefix(b, []byte("\x89PNG")) {
+		t.Errorf("wrong asset: %s, %d bytes", contentType, len(b))
+	}
+}
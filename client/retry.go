@@ -0,0 +1,137 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package client
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultMaxRetries = 3
+	defaultRetryDelay = 2 * time.Second
+	// maxRetryDelay caps the exponential backoff.
+	maxRetryDelay = time.Minute
+	// maxRetryAfter is the longest Retry-After that is waited for. Longer waits give up.
+	maxRetryAfter = 5 * time.Minute
+)
+
+func (c *WANetwork) maxRetries() int {
+	if c.options.MaxRetries < 0 {
+		return 0
+	} else if c.options.MaxRetries == 0 {
+		return defaultMaxRetries
+	}
+	return c.options.MaxRetries
+}
+
+// retryableStatus reports whether a response status is worth another try. 403 and 404 never
+// change, so they are not retried.
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff returns the wait before retry number attempt (starting at 0): exponential, with jitter
+// so that concurrent requests do not come back at the same moment.
+func (c *WANetwork) backoff(attempt int) time.Duration {
+	base := c.options.RetryDelay
+	if base <= 0 {
+		base = defaultRetryDelay
+	}
+	d := base << uint(attempt)
+	if d > maxRetryDelay || d <= 0 {
+		d = maxRetryDelay
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// retryAfter parses the Retry-After header of a 429 or 503 response, in seconds or as a date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// retryWait decides whether a failed attempt is retried, and how long to wait first.
+func (c *WANetwork) retryWait(attempt int, resp *http.Response, err error) (time.Duration, string, bool) {
+	if attempt >= c.maxRetries() {
+		return 0, "", false
+	}
+	var notRecorded *NotRecordedError
+	if errors.As(err, &notRecorded) {
+		// a retry finds the same recordings
+		return 0, "", false
+	}
+	if err != nil {
+		// network errors and timeouts
+		return c.backoff(attempt), err.Error(), true
+	}
+	if !retryableStatus(resp.StatusCode) {
+		return 0, "", false
+	}
+	reason := fmt.Sprintf("status %d", resp.StatusCode)
+	if d, ok := retryAfter(resp); ok {
+		if d > maxRetryAfter {
+			return 0, "", false
+		}
+		return d, reason + " with Retry-After", true
+	}
+	return c.backoff(attempt), reason, true
+}
+
+// rewindBody prepares a request to be sent again. Requests whose body cannot be read again are
+// not retried.
+func rewindBody(req *http.Request) bool {
+	if req.Body == nil || req.Body == http.NoBody {
+		return true
+	}
+	if req.GetBody == nil {
+		return false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return false
+	}
+	req.Body = body
+	return true
+}
+
+// discard closes a response that will be replaced by a retry.
+func discard(resp *http.Response) {
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+}
+
+func (c *WANetwork) logRetry(req *http.Request, reason string, wait time.Duration, attempt int) {
+	fmt.Fprintf(os.Stderr, "[retry] %s %s: %s, retrying in %s (%d/%d)\n",
+		req.Method, req.URL.String(), reason, wait.Round(100*time.Millisecond), attempt+1, c.maxRetries())
+}
@@ -0,0 +1,130 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package client
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func response(code int, retryAfter string) *http.Response {
+	resp := &http.Response{StatusCode: code, Header: make(http.Header)}
+	if retryAfter != "" {
+		resp.Header.Set("Retry-After", retryAfter)
+	}
+	return resp
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name string
+		resp *http.Response
+		ok   bool
+		min  time.Duration
+		max  time.Duration
+	}{
+		{"503 seconds", response(503, "2"), true, 2 * time.Second, 2 * time.Second},
+		{"429 seconds", response(429, "0"), true, 0, 0},
+		{"503 date", response(503, time.Now().Add(10*time.Second).UTC().Format(http.TimeFormat)), true, 8 * time.Second, 10 * time.Second},
+		{"503 date in the past", response(503, time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)), true, 0, 0},
+		{"500 ignores header", response(500, "2"), false, 0, 0},
+		{"503 without header", response(503, ""), false, 0, 0},
+		{"503 bad header", response(503, "soon"), false, 0, 0},
+		{"503 negative", response(503, "-5"), false, 0, 0},
+	}
+	for _, tt := range tests {
+		d, ok := retryAfter(tt.resp)
+		if ok != tt.ok || d < tt.min || d > tt.max {
+			t.Errorf("%s: got %s, %v; expected %v between %s and %s", tt.name, d, ok, tt.ok, tt.min, tt.max)
+		}
+	}
+}
+
+func TestRetryWait(t *testing.T) {
+	c := &WANetwork{options: Options{MaxRetries: 3, RetryDelay: time.Second}}
+	noRetries := &WANetwork{options: Options{MaxRetries: -1}}
+	netErr := &url.Error{Op: "Get", URL: "http://whateleyacademy.net/", Err: errors.New("connection reset")}
+	notRecorded := &url.Error{Op: "Get", URL: "http://whateleyacademy.net/",
+		Err: errors.WithStack(&NotRecordedError{Method: "GET", URL: "http://whateleyacademy.net/", File: "x.http"})}
+
+	tests := []struct {
+		name    string
+		c       *WANetwork
+		attempt int
+		resp    *http.Response
+		err     error
+		retry   bool
+		min     time.Duration
+		max     time.Duration
+	}{
+		{"200", c, 0, response(200, ""), nil, false, 0, 0},
+		{"403", c, 0, response(403, ""), nil, false, 0, 0},
+		{"404", c, 0, response(404, ""), nil, false, 0, 0},
+		{"500 backoff", c, 0, response(500, ""), nil, true, 500 * time.Millisecond, time.Second},
+		{"502 second backoff", c, 1, response(502, ""), nil, true, time.Second, 2 * time.Second},
+		{"503 Retry-After seconds", c, 0, response(503, "2"), nil, true, 2 * time.Second, 2 * time.Second},
+		{"429 Retry-After date", c, 0, response(429, time.Now().Add(30*time.Second).UTC().Format(http.TimeFormat)), nil, true, 28 * time.Second, 30 * time.Second},
+		{"503 Retry-After too long", c, 0, response(503, "3600"), nil, false, 0, 0},
+		{"network error", c, 0, nil, netErr, true, 500 * time.Millisecond, time.Second},
+		{"not recorded", c, 0, nil, notRecorded, false, 0, 0},
+		{"out of retries", c, 3, response(500, ""), nil, false, 0, 0},
+		{"retries off", noRetries, 0, response(500, ""), nil, false, 0, 0},
+	}
+	for _, tt := range tests {
+		d, _, retry := tt.c.retryWait(tt.attempt, tt.resp, tt.err)
+		if retry != tt.retry || d < tt.min || d > tt.max {
+			t.Errorf("%s: got %s, %v; expected %v between %s and %s", tt.name, d, retry, tt.retry, tt.min, tt.max)
+		}
+	}
+}
+
+func TestRewindBody(t *testing.T) {
+	withGetBody, _ := http.NewRequest("POST", "http://whateleyacademy.net/", bytes.NewReader([]byte("x")))
+	withoutGetBody, _ := http.NewRequest("POST", "http://whateleyacademy.net/", ioutil.NopCloser(strings.NewReader("x")))
+	noBody, _ := http.NewRequest("GET", "http://whateleyacademy.net/", nil)
+	if !rewindBody(withGetBody) || rewindBody(withoutGetBody) || !rewindBody(noBody) {
+		t.Errorf("rewindBody: got %v %v %v", rewindBody(withGetBody), rewindBody(withoutGetBody), rewindBody(noBody))
+	}
+}
+
+func TestDoRetries(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	c := New(Options{MaxRetries: 2, RetryDelay: time.Millisecond})
+
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	_, err := c.Document(req)
+	var status *HTTPStatusError
+	if !errors.As(err, &status) || status.StatusCode != 503 {
+		t.Errorf("expected a 503 error, got %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Errorf("GET: expected 3 requests, got %d", n)
+	}
+
+	// the body cannot be sent twice
+	atomic.StoreInt32(&requests, 0)
+	req, _ = http.NewRequest("POST", srv.URL, ioutil.NopCloser(strings.NewReader("x")))
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("POST without GetBody: expected 1 request, got %d", n)
+	}
+}
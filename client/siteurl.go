@@ -0,0 +1,126 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package client
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultBaseURL is the address of the Crystal Hall.
+const DefaultBaseURL = "http://whateleyacademy.net/"
+
+// defaultSiteHost is always accepted in canonical links, as mirrors and saved pages keep the
+// links of the real site.
+const defaultSiteHost = "whateleyacademy.net"
+
+var defaultBaseURL, _ = ParseBaseURL(DefaultBaseURL)
+
+// ParseBaseURL checks a site base URL, e.g. "https://whateleyacademy.net/" or a mirror with a
+// path prefix ("http://mirror.example/wa/"). The path of the result ends with a slash.
+func ParseBaseURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "bad site base URL")
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.Errorf("bad site base URL %q: must be an http or https URL", s)
+	}
+	u.RawQuery = ""
+	u.Fragment = ""
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	u.RawPath = ""
+	return u, nil
+}
+
+// orDefault returns base, or the Crystal Hall if base is nil.
+func orDefault(base *url.URL) *url.URL {
+	if base == nil {
+		return defaultBaseURL
+	}
+	return base
+}
+
+// BaseURL returns the site base URL of the client, see Options.BaseURL.
+func (c *WANetwork) BaseURL() *url.URL {
+	u := *orDefault(c.baseURL)
+	return &u
+}
+
+// hostKey makes host names comparable.
+func hostKey(host string) string {
+	return strings.TrimPrefix(strings.ToLower(host), "www.")
+}
+
+// IsSiteHost reports whether host is the Crystal Hall, with or without "www.".
+func IsSiteHost(host string) bool {
+	return hostKey(host) == defaultSiteHost
+}
+
+func isSiteHost(base *url.URL, host string) bool {
+	return IsSiteHost(host) || hostKey(host) == hostKey(orDefault(base).Host)
+}
+
+// IsSiteHost reports whether host is the client's site or the Crystal Hall, with or without
+// "www.".
+func (c *WANetwork) IsSiteHost(host string) bool {
+	return isSiteHost(c.baseURL, host)
+}
+
+// sitePrefix is the path prefix of the base URL without the final slash, e.g. "/wa", or "".
+func sitePrefix(base *url.URL) string {
+	return strings.TrimSuffix(base.EscapedPath(), "/")
+}
+
+// sitePath returns the path of u below the site base URL, starting with a slash, and whether u is
+// on the site at all. http and https are both accepted.
+func sitePath(base *url.URL, u *url.URL) (string, bool) {
+	base = orDefault(base)
+	if (u.Scheme != "http" && u.Scheme != "https") || !isSiteHost(base, u.Host) {
+		return "", false
+	}
+	p := u.EscapedPath()
+	if prefix := sitePrefix(base); prefix != "" && hostKey(u.Host) == hostKey(base.Host) {
+		if !strings.HasPrefix(p, prefix+"/") {
+			return "", false
+		}
+		p = strings.TrimPrefix(p, prefix)
+	}
+	return p, true
+}
+
+// ResolveReference resolves a link found in a page of the site. Paths starting with a slash are
+// put below the path prefix of the base URL, unless they already start with it.
+func (c *WANetwork) ResolveReference(ref *url.URL) *url.URL {
+	base := c.BaseURL()
+	if ref.Scheme == "" && ref.Host == "" && strings.HasPrefix(ref.Path, "/") {
+		prefix := sitePrefix(base)
+		if prefix != "" && !strings.HasPrefix(ref.EscapedPath(), prefix+"/") {
+			r := *ref
+			r.Path = strings.TrimPrefix(ref.Path, "/")
+			r.RawPath = ""
+			ref = &r
+		}
+	}
+	return base.ResolveReference(ref)
+}
+
+func siteURL(base *url.URL, p string) string {
+	base = orDefault(base)
+	ref, err := url.Parse(strings.TrimPrefix(p, "/"))
+	if err != nil {
+		return base.String() + strings.TrimPrefix(p, "/")
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// SiteURL returns the absolute URL of a path on the client's site, e.g. "images/stories/x.png" or
+// "index.php/faq-help".
+func (c *WANetwork) SiteURL(p string) string {
+	return siteURL(c.baseURL, p)
+}
@@ -0,0 +1,84 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package client
+
+import (
+	"net/url"
+	"testing"
+)
+
+// Clients with different base URLs must not affect each other.
+func TestBaseURLPerClient(t *testing.T) {
+	real := New(Options{MaxRetries: -1})
+	mirrorURL, err := ParseBaseURL("https://mirror.example/wa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mirror := New(Options{BaseURL: mirrorURL, MaxRetries: -1})
+
+	tests := []struct {
+		c        *WANetwork
+		in       string
+		id       string
+		storyURL string
+	}{
+		{real, "https://www.whateleyacademy.net/index.php/original-timeline/12-foo#x", "12", "http://whateleyacademy.net/index.php/original-timeline/12-foo"},
+		{real, "https://mirror.example/wa/index.php/original-timeline/13-bar", "", ""},
+		{mirror, "http://whateleyacademy.net/index.php/original-timeline/12-foo", "12", "https://mirror.example/wa/index.php/original-timeline/12-foo"},
+		{mirror, "https://mirror.example/wa/index.php/original-timeline/13-bar", "13", "https://mirror.example/wa/index.php/original-timeline/13-bar"},
+		{mirror, "https://mirror.example/index.php/original-timeline/13-bar", "", ""},
+	}
+	for _, tt := range tests {
+		u, err := tt.c.ParseURL(tt.in)
+		if tt.id == "" {
+			if err == nil {
+				t.Errorf("%s (base %s): expected an error, got %+v", tt.in, tt.c.BaseURL(), u)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s (base %s): %v", tt.in, tt.c.BaseURL(), err)
+			continue
+		}
+		if u.StoryID != tt.id || u.URL() != tt.storyURL {
+			t.Errorf("%s (base %s): got #%s %s, expected #%s %s", tt.in, tt.c.BaseURL(), u.StoryID, u.URL(), tt.id, tt.storyURL)
+		}
+	}
+
+	ref, _ := url.Parse("/images/b.png")
+	if got := real.ResolveReference(ref).String(); got != "http://whateleyacademy.net/images/b.png" {
+		t.Errorf("real site resolved /images/b.png to %s", got)
+	}
+	if got := mirror.ResolveReference(ref).String(); got != "https://mirror.example/wa/images/b.png" {
+		t.Errorf("mirror resolved /images/b.png to %s", got)
+	}
+	if real.IsSiteHost("mirror.example") || !mirror.IsSiteHost("mirror.example") {
+		t.Errorf("IsSiteHost mixed up the clients")
+	}
+}
+
+func TestParseBaseURL(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{"http://whateleyacademy.net", "http://whateleyacademy.net/"},
+		{"https://mirror.example/wa?x=1#top", "https://mirror.example/wa/"},
+		{"http://127.0.0.1:8080/", "http://127.0.0.1:8080/"},
+		{"whateleyacademy.net", ""},
+		{"ftp://whateleyacademy.net/", ""},
+		{"http://", ""},
+		{"http://%zz/", ""},
+	}
+	for _, tt := range tests {
+		u, err := ParseBaseURL(tt.in)
+		if tt.out == "" {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %s", tt.in, u)
+			}
+		} else if err != nil || u.String() != tt.out {
+			t.Errorf("%s: got %v, %v; expected %s", tt.in, u, err, tt.out)
+		}
+	}
+}
@@ -14,6 +14,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/pkg/errors"
+
 	"github.com/riking/whateley-ebooks/client"
 	"github.com/riking/whateley-ebooks/cmd"
 )
@@ -29,26 +31,28 @@ type result struct {
 func getStory(ch chan<- *client.WhateleyPage, storyID string, networkAccess *client.WANetwork) {
 	story, err := networkAccess.GetStoryByID(storyID)
 	if err != nil {
-		if strings.Contains(err.Error(), "fetching page HTML") {
-			if strings.Contains(err.Error(), "404 for http") {
-				fmt.Fprint(os.Stderr, "4")
-				return
-			}
-			if strings.Contains(err.Error(), "403 for http") {
-				fmt.Fprint(os.Stderr, "3")
-				return
-			}
-			fmt.Fprintf(os.Stderr, "\n[W] Ignoring error fetching HTML for %s: %s\n", storyID, err)
+		var notAStory *client.NotAStoryError
+		switch {
+		case errors.Is(err, client.ErrNotFound):
+			fmt.Fprint(os.Stderr, "4")
 			return
-		}
-		if strings.Contains(err.Error(), "Could not parse canonical URL") {
-			if strings.Contains(err.Error(), "/community") {
+		case errors.Is(err, client.ErrForbidden):
+			fmt.Fprint(os.Stderr, "3")
+			return
+		case errors.As(err, &notAStory):
+			if notAStory.Category == "community" {
 				fmt.Fprint(os.Stderr, "C")
 				return
 			}
 			fmt.Fprintln(os.Stderr, err)
 			return
 		}
+		var fetchErr *client.FetchError
+		if errors.As(err, &fetchErr) {
+			fmt.Fprintf(os.Stderr, "\n[W] Ignoring error fetching HTML for %s: %s\n", storyID, err)
+			return
+		}
+		// cache and parse errors
 		fmt.Println(err)
 		os.Exit(1)
 	}
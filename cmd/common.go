@@ -7,6 +7,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/riking/whateley-ebooks/client"
 	"github.com/riking/whateley-ebooks/ebooks"
@@ -18,15 +19,47 @@ func Setup() *client.WANetwork {
 	ebooks.SetTyposFromFile("./typos.yml")
 	offlineMode = flag.Bool("offline", false, "Operate in offline mode (cached entries never expire).")
 	maxRequests := flag.Int("max-requests", 10, "Maximum number of concurrent outstanding HTTP requests")
+	siteURL := flag.String("site", client.DefaultBaseURL, "Base URL of the site to download stories from, e.g. https://whateleyacademy.net/ or a mirror")
+	assetHosts := flag.String("asset-hosts", "", "Comma-separated list of hosts that images may be downloaded from, besides the site (default: any host)")
+	retries := flag.Int("retries", 3, "How often to retry a request after a network error or a 5xx or 429 response (0 to never retry)")
+	recordDir := flag.String("record", "", "Save every HTTP response to this directory, for -replay")
+	replayDir := flag.String("replay", "", "Answer HTTP requests with the responses saved by -record in this directory, without using the network")
+	legacyReport := flag.Bool("legacy-report", false, "Print the inline styles and deprecated markup that could not be converted to classes")
 
 	flag.Parse()
 
-	networkAccess := client.New(client.Options{
+	if *legacyReport {
+		ebooks.SetLegacyReport(os.Stdout)
+	}
+
+	baseURL, err := client.ParseBaseURL(*siteURL)
+	if err != nil {
+		Fatal(err)
+	}
+
+	opts := client.Options{
 		UserAgent:      "(Error: tool name not specified) (+github.com/riking/whateley-ebooks)",
 		CacheFile:      "./cache.db",
 		Offline:        *offlineMode,
 		MaxConcurrency: *maxRequests,
-	})
+		BaseURL:        baseURL,
+		MaxRetries:     *retries,
+	}
+	if *retries == 0 {
+		opts.MaxRetries = -1
+	}
+	if *assetHosts != "" {
+		opts.AssetHosts = strings.Split(*assetHosts, ",")
+	}
+	if *recordDir != "" && *replayDir != "" {
+		fmt.Println("-record and -replay cannot be used together")
+		os.Exit(1)
+	} else if *recordDir != "" {
+		opts.Transport = &client.RecordingTransport{Dir: *recordDir}
+	} else if *replayDir != "" {
+		opts.Transport = &client.ReplayTransport{Dir: *replayDir}
+	}
+	networkAccess := client.New(opts)
 
 	return networkAccess
 }
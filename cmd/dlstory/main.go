@@ -4,6 +4,7 @@
 package main // import "github.com/riking/whateley-ebooks/cmd/dlstory"
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -15,7 +16,7 @@ import (
 )
 
 func getPage(url string, access *client.WANetwork) (*client.WhateleyPage, error) {
-	u, _ := client.ParseURL(url)
+	u, _ := access.ParseURL(url)
 	return access.GetStoryByID(u.StoryID)
 }
 
@@ -26,6 +27,7 @@ func fatal(err error) {
 }
 
 var purgeCache = flag.Bool("purge", false, "Purge cache for the page")
+var textFormat = flag.String("format", "", "Also write the processed story as text: txt (dlstory-after.txt) or md (dlstory-after.md)")
 
 func main() {
 	// flag.String()
@@ -64,4 +66,21 @@ func main() {
 	ioutil.WriteFile("dlstory-after.html", []byte(page.StoryBody()), 0644)
 	html, _ := page.Doc().Html()
 	ioutil.WriteFile("dlstory-full.html", []byte(html), 0644)
+
+	if *textFormat != "" {
+		var buf bytes.Buffer
+		switch *textFormat {
+		case "txt":
+			err = ebooks.RenderStoryText(&buf, page, ebooks.PlainText)
+		case "md":
+			err = ebooks.RenderStoryText(&buf, page, ebooks.Markdown)
+		default:
+			fmt.Println("Unknown -format, expected txt or md")
+			os.Exit(1)
+		}
+		if err != nil {
+			cmd.Fatal(err)
+		}
+		ioutil.WriteFile(fmt.Sprintf("dlstory-after.%s", *textFormat), buf.Bytes(), 0644)
+	}
 }
@@ -0,0 +1,53 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package main // import "github.com/riking/whateley-ebooks/cmd/fakesite"
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/riking/whateley-ebooks/client/fakesite"
+	"github.com/riking/whateley-ebooks/cmd"
+)
+
+var listenAddr = flag.String("listen", "127.0.0.1:8080", "Address to serve the fake site on")
+var configFile = flag.String("config", "", "YAML file with the stories and assets to serve (default: a small demo site)")
+var delay = flag.Duration("delay", 0, "Wait this long before answering every request")
+
+func main() {
+	flag.Parse()
+
+	site := fakesite.Demo()
+	if *configFile != "" {
+		var err error
+		site, err = fakesite.LoadConfig(*configFile)
+		if err != nil {
+			cmd.Fatal(err)
+		}
+	}
+	if *delay != 0 {
+		site.Delay = *delay
+	}
+	if site.BaseURL == "" {
+		// canonical links point back here, so that the tools can use it with -site
+		site.BaseURL = "http://" + *listenAddr
+	}
+
+	fmt.Printf("Serving stories %v on http://%s/\n", site.StoryIDs(), *listenAddr)
+	fmt.Printf("Use it with: make-ebook -site %s ...\n", site.BaseURL)
+	server := &http.Server{
+		Addr:         *listenAddr,
+		Handler:      site,
+		ReadTimeout:  time.Minute,
+		WriteTimeout: 5 * time.Minute,
+	}
+	err := server.ListenAndServe()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+}
@@ -9,8 +9,10 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
@@ -18,10 +20,20 @@ import (
 	"github.com/riking/whateley-ebooks/client"
 	"github.com/riking/whateley-ebooks/cmd"
 	"github.com/riking/whateley-ebooks/ebooks"
+	"github.com/riking/whateley-ebooks/ebooks/validate"
 )
 
+var validateOutput = flag.Bool("validate", false, "Check the structure of each generated epub file")
+var outputFormat = flag.String("format", "epub", "Output format: epub, kepub (for Kobo readers), fb2, html for a single self-contained web page, txt, or md")
+var reproducible = flag.Bool("reproducible", false, "Make byte-for-byte identical files on every build. The build time is taken from SOURCE_DATE_EPOCH if set, and is 1980-01-01 otherwise")
+var createdBy = flag.String("created-by", "", "Name to record as the creator of the files (default: the created-by setting, or user@hostname)")
+var profileNames = flag.String("profile", "", "Comma-separated device profiles (kindle-paperwhite, kobo, phone, desktop). One file is written per profile, named {book}-{profile}")
+var templateDir = flag.String("template-dir", "templates", "Directory with replacements for the built-in templates and story.css, used when it has a file of the same name")
+var epubVersion = flag.Int("epub-version", 2, "EPUB version to write (2 or 3) for book definitions that do not specify one")
+
 func createEbook(bookID string, networkAccess *client.WANetwork) error {
 	var ebooksFile *ebooks.EpubDefinition
+	var definitionFile string
 	attemptFiles := []string{bookID, fmt.Sprintf("book-definitions/%s", bookID), fmt.Sprintf("book-definitions/%s.yml", bookID)}
 	for _, v := range attemptFiles {
 		_, err := os.Stat(v)
@@ -37,27 +49,133 @@ func createEbook(bookID string, networkAccess *client.WANetwork) error {
 		if err != nil {
 			return errors.Wrapf(err, "Could not parse %s", v)
 		}
+		definitionFile = v
+	}
+	if ebooksFile == nil {
+		return errors.Errorf("Could not find book definition %s", bookID)
+	}
+
+	err := ebooksFile.LoadTemplates(path.Dir(definitionFile))
+	if err != nil {
+		return errors.Wrapf(err, "Could not load templates for %s", definitionFile)
+	}
+	err = ebooksFile.LoadFonts(path.Dir(definitionFile))
+	if err != nil {
+		return errors.Wrapf(err, "Could not load fonts for %s", definitionFile)
+	}
+
+	if ebooksFile.EpubVersion == 0 {
+		ebooksFile.EpubVersion = *epubVersion
+	}
+	if *createdBy != "" {
+		ebooksFile.CreatedBy = *createdBy
+	}
+	if buildTime, ok, err := reproducibleBuildTime(); err != nil {
+		return err
+	} else if ok {
+		ebooksFile.SetReproducible(buildTime)
 	}
 
-	var outFile string = fmt.Sprintf("target/%s.epub", strings.TrimSuffix(path.Base(bookID), ".yml"))
+	var profiles []*ebooks.Profile
+	if *profileNames != "" {
+		for _, name := range strings.Split(*profileNames, ",") {
+			profile, err := ebooks.GetProfile(strings.TrimSpace(name))
+			if err != nil {
+				return err
+			}
+			profiles = append(profiles, profile)
+		}
+	}
 
-	err := ebooksFile.Prepare(networkAccess)
+	err = ebooksFile.Prepare(networkAccess)
 	if err != nil {
 		return errors.Wrapf(err, "Failed to prepare %s", bookID)
 	}
 
-	err = ebooks.CreateEpub(ebooksFile, networkAccess, outFile)
+	baseName := strings.TrimSuffix(path.Base(bookID), ".yml")
+	if len(profiles) == 0 {
+		return writeBook(ebooksFile, baseName, networkAccess)
+	}
+	// one variant of the book per profile, sharing the downloaded and processed stories
+	for _, profile := range profiles {
+		variant := ebooksFile.Clone()
+		variant.SetProfile(profile)
+		err = writeBook(variant, fmt.Sprintf("%s-%s", baseName, profile.Name), networkAccess)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeBook writes target/{name}.{format} and validates it if requested.
+func writeBook(ebooksFile *ebooks.EpubDefinition, name string, networkAccess *client.WANetwork) error {
+	var outFile string = fmt.Sprintf("target/%s.%s", name, *outputFormat)
+	if *outputFormat == "kepub" {
+		outFile += ".epub"
+	}
+
+	var err error
+	switch *outputFormat {
+	case "epub":
+		err = ebooks.CreateEpub(ebooksFile, networkAccess, outFile)
+	case "kepub":
+		err = ebooks.CreateKepub(ebooksFile, networkAccess, outFile)
+	case "fb2":
+		err = ebooks.CreateFB2(ebooksFile, networkAccess, outFile)
+	case "html":
+		err = ebooks.CreateHTML(ebooksFile, networkAccess, outFile)
+	case "txt":
+		err = ebooks.CreateText(ebooksFile, networkAccess, outFile, ebooks.PlainText)
+	case "md":
+		err = ebooks.CreateText(ebooksFile, networkAccess, outFile, ebooks.Markdown)
+	default:
+		return errors.Errorf("Unknown output format %s", *outputFormat)
+	}
 	if err != nil {
-		return errors.Wrapf(err, "Failed to create %s", bookID)
+		return errors.Wrapf(err, "Failed to create %s", name)
+	}
+
+	if *validateOutput && (*outputFormat == "epub" || *outputFormat == "kepub") {
+		problems, err := validate.ValidateFile(outFile, ebooksFile.Sources())
+		if err != nil {
+			return errors.Wrapf(err, "Failed to validate %s", name)
+		}
+		for _, v := range problems {
+			fmt.Printf("[validate] %s: %s\n", outFile, v)
+		}
+		if len(problems) > 0 {
+			return errors.Errorf("%s has %d structural problems", outFile, len(problems))
+		}
 	}
 	return nil
 }
 
+// reproducibleBuildTime returns the fixed build time for -reproducible builds.
+// Setting SOURCE_DATE_EPOCH (see https://reproducible-builds.org/specs/source-date-epoch/) also
+// turns on reproducible builds.
+func reproducibleBuildTime() (time.Time, bool, error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch != "" {
+		sec, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return time.Time{}, false, errors.Wrap(err, "Bad value for SOURCE_DATE_EPOCH")
+		}
+		return time.Unix(sec, 0).UTC(), true, nil
+	}
+	if *reproducible {
+		// earliest time that can be stored in a zip file
+		return time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC), true, nil
+	}
+	return time.Time{}, false, nil
+}
+
 func main() {
 	// flag.String()
 
 	networkAccess := cmd.Setup()
 	networkAccess.UserAgent("Ebook tool - Make EPub (+github.com/riking/whateley-ebooks)")
+	ebooks.SetTemplateDir(*templateDir)
 
 	bookIDs := flag.Args()
 	if len(bookIDs) == 0 {
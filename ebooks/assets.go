@@ -0,0 +1,285 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package ebooks
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/pkg/errors"
+
+	"github.com/riking/whateley-ebooks/client"
+)
+
+// Images in story bodies that are not in the definition's Assets list are found, downloaded, and
+// bundled automatically. The explicit Assets list still takes precedence.
+
+// A bundledImage is an image found in a story body, or an entry of the Assets list.
+type bundledImage struct {
+	// absolute URL of the image
+	Source string
+	// file name under OEBPS/Images/
+	Target      string
+	body        []byte
+	contentType string
+}
+
+// imageState is the shared record of the images found by preparePageB.
+// It is guarded by EpubDefinition.imageLock, as stories are processed in parallel.
+type imageState struct {
+	bySource map[string]*bundledImage
+	// images that matched an entry in Assets
+	fromAssets map[string]bool
+	// reason the image could not be fetched, by URL
+	failed map[string]string
+}
+
+func (s imageState) clone() imageState {
+	if s.bySource == nil {
+		return imageState{}
+	}
+	c := imageState{
+		bySource:   make(map[string]*bundledImage),
+		fromAssets: make(map[string]bool),
+		failed:     make(map[string]string),
+	}
+	for k, v := range s.bySource {
+		c.bySource[k] = v
+	}
+	for k, v := range s.fromAssets {
+		c.fromAssets[k] = v
+	}
+	for k, v := range s.failed {
+		c.failed[k] = v
+	}
+	return c
+}
+
+// errImageFailed is returned by fetchImage for an image that has already been reported as failed.
+var errImageFailed = errors.New("image could not be fetched")
+
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// bundledImageName picks the name of an image in the book.
+// The hash of the URL keeps the name unique and stable no matter which story finds the image first.
+func bundledImageName(u *url.URL, contentType string) string {
+	ext := path.Ext(u.Path)
+	stem := strings.TrimSuffix(path.Base(u.Path), ext)
+	stem = strings.Trim(unsafeFilenameChars.ReplaceAllString(stem, "-"), "-")
+	if stem == "" {
+		stem = "image"
+	}
+	if ext == "" || unsafeFilenameChars.MatchString(ext[1:]) {
+		ext = ""
+		if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+	sum := sha1.Sum([]byte(u.String()))
+	return fmt.Sprintf("%s-%s%s", stem, hex.EncodeToString(sum[:4]), strings.ToLower(ext))
+}
+
+// imageID is the manifest id of an image in the book, also used for FB2 binaries. Image names can
+// start with a digit, which an XML ID cannot.
+func imageID(target string) string {
+	return "img-" + unsafeFilenameChars.ReplaceAllString(target, "-")
+}
+
+// assetDownload returns the URL of an entry of the Assets list, by default images/Target on the
+// site.
+func assetDownload(access *client.WANetwork, download, target string) string {
+	if download == "" {
+		return access.SiteURL("images/" + target)
+	}
+	return download
+}
+
+// resolveImageURL turns the src attribute of an image into an absolute URL.
+// ok is false for images that are already part of the book.
+func resolveImageURL(access *client.WANetwork, src string) (u *url.URL, ok bool) {
+	if src == "" || strings.HasPrefix(src, "../Images/") || strings.HasPrefix(src, "data:") {
+		return nil, false
+	}
+	ref, err := url.Parse(strings.TrimSpace(src))
+	if err != nil {
+		return nil, false
+	}
+	u = access.ResolveReference(ref)
+	u.Fragment = ""
+	return u, true
+}
+
+func (ed *EpubDefinition) recordImageFailure(source string, err error) {
+	ed.imageLock.Lock()
+	ed.images.failed[source] = err.Error()
+	ed.imageLock.Unlock()
+}
+
+// fetchImage downloads an image, or returns the copy another story already downloaded.
+func (ed *EpubDefinition) fetchImage(access *client.WANetwork, u *url.URL) (*bundledImage, error) {
+	source := u.String()
+
+	ed.imageLock.Lock()
+	img, ok := ed.images.bySource[source]
+	_, failed := ed.images.failed[source]
+	ed.imageLock.Unlock()
+	if ok {
+		return img, nil
+	} else if failed {
+		return nil, errImageFailed
+	}
+
+	req, err := http.NewRequest("GET", source, nil)
+	if err != nil {
+		return nil, err
+	}
+	body, contentType, err := access.GetAsset(req)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, errors.Errorf("not an image, got Content-Type %s", contentType)
+	}
+
+	ed.imageLock.Lock()
+	defer ed.imageLock.Unlock()
+	if img, ok := ed.images.bySource[source]; ok {
+		// fetched by another story at the same time
+		return img, nil
+	}
+	img = &bundledImage{
+		Source:      source,
+		Target:      bundledImageName(u, contentType),
+		body:        body,
+		contentType: contentType,
+	}
+	ed.images.bySource[source] = img
+	return img, nil
+}
+
+// bundleImages finds every image in the story body that is not part of the book yet, downloads it,
+// and points its src at the copy in the book.
+// Images that cannot be fetched are left alone and listed by printImageSummary.
+func (ed *EpubDefinition) bundleImages(access *client.WANetwork, page *client.WhateleyPage) {
+	ed.imageLock.Lock()
+	if ed.images.bySource == nil {
+		ed.images = imageState{
+			bySource:   make(map[string]*bundledImage),
+			fromAssets: make(map[string]bool),
+			failed:     make(map[string]string),
+		}
+	}
+	ed.imageLock.Unlock()
+
+	explicit := make(map[string]string)
+	for _, asset := range ed.Assets {
+		if u, ok := resolveImageURL(access, assetDownload(access, asset.Download, asset.Target)); ok {
+			explicit[u.String()] = asset.Target
+		}
+	}
+
+	page.StoryBodySelection().Find("img").Each(func(_ int, s *goquery.Selection) {
+		src, _ := s.Attr("src")
+		u, ok := resolveImageURL(access, src)
+		if !ok {
+			return
+		}
+		if target, ok := explicit[u.String()]; ok {
+			ed.imageLock.Lock()
+			ed.images.fromAssets[u.String()] = true
+			ed.imageLock.Unlock()
+			s.SetAttr("src", fmt.Sprintf("../Images/%s", target))
+			return
+		}
+
+		img, err := ed.fetchImage(access, u)
+		if err != nil {
+			if err != errImageFailed {
+				ed.recordImageFailure(u.String(), err)
+			}
+			return
+		}
+		s.SetAttr("src", fmt.Sprintf("../Images/%s", img.Target))
+	})
+}
+
+// absoluteImageURLs points every image in the story body at its full address on the site, for
+// output formats that do not bundle images.
+func absoluteImageURLs(access *client.WANetwork, page *client.WhateleyPage) {
+	page.StoryBodySelection().Find("img").Each(func(_ int, s *goquery.Selection) {
+		src, _ := s.Attr("src")
+		if u, ok := resolveImageURL(access, src); ok {
+			s.SetAttr("src", u.String())
+		}
+	})
+}
+
+// collectImages downloads the images in the Assets list, and returns them followed by the
+// automatically found images.
+func (ed *EpubDefinition) collectImages(access *client.WANetwork) ([]*bundledImage, error) {
+	var images []*bundledImage
+	for _, v := range ed.Assets {
+		v.Download = assetDownload(access, v.Download, v.Target)
+
+		req, err := http.NewRequest("GET", v.Download, nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "downloading asset %s", v.Download)
+		}
+		body, contentType, err := access.GetAsset(req)
+		if err != nil {
+			return nil, errors.Wrapf(err, "downloading asset %s", v.Download)
+		}
+		images = append(images, &bundledImage{
+			Source:      v.Download,
+			Target:      v.Target,
+			body:        body,
+			contentType: contentType,
+		})
+	}
+	images = append(images, ed.bundledImages()...)
+	for i := range images {
+		images[i] = ed.profile.scaleImage(images[i])
+	}
+	return images, nil
+}
+
+// bundledImages returns the automatically found images, sorted by file name.
+func (ed *EpubDefinition) bundledImages() []*bundledImage {
+	ed.imageLock.Lock()
+	defer ed.imageLock.Unlock()
+	list := make([]*bundledImage, 0, len(ed.images.bySource))
+	for _, v := range ed.images.bySource {
+		list = append(list, v)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Target < list[j].Target
+	})
+	return list
+}
+
+func (ed *EpubDefinition) printImageSummary(w io.Writer) {
+	ed.imageLock.Lock()
+	defer ed.imageLock.Unlock()
+	found := len(ed.images.bySource) + len(ed.images.fromAssets) + len(ed.images.failed)
+	fmt.Fprintf(w, "Images: %d found in stories, %d from assets list, %d fetched, %d could not be fetched\n",
+		found, len(ed.images.fromAssets), len(ed.images.bySource), len(ed.images.failed))
+	failed := make([]string, 0, len(ed.images.failed))
+	for k := range ed.images.failed {
+		failed = append(failed, k)
+	}
+	sort.Strings(failed)
+	for _, k := range failed {
+		fmt.Fprintf(w, "  [ERR] %s: %s\n", k, ed.images.failed[k])
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package ebooks
+
+import (
+	"net/url"
+	"regexp"
+	"testing"
+)
+
+// xmlIDRegexp matches the XML IDs this program can produce.
+var xmlIDRegexp = regexp.MustCompile(`\A[A-Za-z_][A-Za-z0-9_.-]*\z`)
+
+func TestBundledImageName(t *testing.T) {
+	tests := []struct {
+		src         string
+		contentType string
+		name        string
+	}{
+		{"http://whateleyacademy.net/images/stories/demo.png", "image/png", "demo-"},
+		{"http://whateleyacademy.net/images/2016/1.JPG", "image/jpeg", "1-"},
+		{"http://example.com/get.php?id=3", "image/gif", "get-"},
+		{"http://example.com/images/", "image/png", "images-"},
+		{"http://example.com/%E2%98%83.png", "image/png", "image-"},
+	}
+	seen := make(map[string]string)
+	for _, tt := range tests {
+		u, err := url.Parse(tt.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := bundledImageName(u, tt.contentType)
+		if len(name) < len(tt.name) || name[:len(tt.name)] != tt.name {
+			t.Errorf("%s: got %s, expected it to start with %s", tt.src, name, tt.name)
+		}
+		if other, ok := seen[name]; ok {
+			t.Errorf("%s and %s both map to %s", tt.src, other, name)
+		}
+		seen[name] = tt.src
+		if id := imageID(name); !xmlIDRegexp.MatchString(id) {
+			t.Errorf("%s: %s is not a valid manifest id", tt.src, id)
+		}
+	}
+}
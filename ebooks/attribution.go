@@ -0,0 +1,105 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package ebooks
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+
+	"github.com/riking/whateley-ebooks/client"
+)
+
+var attributionTmpl = template.Must(template.New("attribution").Parse(string(MustAsset("attribution.html"))))
+
+// storyPageData is the data for part.html.
+type storyPageData struct {
+	*client.WhateleyPage
+	// Attribution is the header from attribution.html, or empty.
+	Attribution template.HTML
+}
+
+// wantsAttribution reports whether the part gets an attribution header. The part setting overrides
+// the book setting.
+func (ed *EpubDefinition) wantsAttribution(t *TOCEntry) bool {
+	if t.Attribution != nil {
+		return *t.Attribution
+	}
+	return ed.Attribution
+}
+
+// renderAttribution renders the title, author, publish date and link of a story.
+func renderAttribution(tmpl *template.Template, page *client.WhateleyPage) (template.HTML, error) {
+	data := struct {
+		Title, Author, Published, URL string
+	}{
+		Title:  page.Title(),
+		Author: page.Authors(),
+		URL:    page.URL(),
+	}
+	published, err := page.PublishDate()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[attribution] #%s: %s\n", page.StoryID, err)
+	} else {
+		data.Published = published.Format("2 January 2006")
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	if err != nil {
+		return "", err
+	}
+	return template.HTML(buf.String()), nil
+}
+
+// storyAuthors lists the authors of the prepared stories, in book order, without duplicates.
+func (ed *EpubDefinition) storyAuthors() []string {
+	var authors []string
+	seen := make(map[string]bool)
+	for _, v := range ed.Parts {
+		if v.Story.page == nil {
+			continue
+		}
+		author := v.Story.page.Authors()
+		if author == "" || seen[author] {
+			continue
+		}
+		seen[author] = true
+		authors = append(authors, author)
+	}
+	return authors
+}
+
+// Contributors are the story authors other than the book author, for dc:contributor.
+func (ed *EpubDefinition) Contributors() []string {
+	var result []string
+	for _, v := range ed.storyAuthors() {
+		if v != ed.Author {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// fillAuthor sets the book author from the story authors if the definition has none.
+func (ed *EpubDefinition) fillAuthor() {
+	if ed.Author != "" {
+		return
+	}
+	authors := ed.storyAuthors()
+	switch len(authors) {
+	case 0:
+		ed.Author = "Unknown Author"
+	case 1:
+		ed.Author = authors[0]
+	case 2, 3:
+		ed.Author = strings.Join(authors[:len(authors)-1], ", ") + " and " + authors[len(authors)-1]
+	default:
+		ed.Author = "Various Authors"
+		if ed.AuthorSort == "" {
+			ed.AuthorSort = "Various"
+		}
+	}
+}
@@ -1,8 +1,13 @@
 // Code generated by go-bindata.
 // sources:
 // content.opf
+// content3.opf
+// nav.xhtml
 // about.html
+// attribution.html
+// book.html
 // cover.html
+// cover-image.html
 // part.html
 // story.css
 // toc.ncx
@@ -73,7 +78,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var _contentOpf = "\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x84\x92\x41\x8b\xdb\x30\x10\x85\xef\xf9\x15\x46\xf7\xb5\xb2\x7b\x29\x18\xdb\x34\x65\x29\xdd\x43\xa1\xb0\xcd\xb9\x28\xd2\x38\x1e\x22\x4b\xaa\x34\xaa\x5b\x8c\xff\x7b\x91\xec\xc4\x0d\x6d\xd9\x9b\x67\xe6\xbd\x4f\xa3\x67\xd5\x4e\xc8\x8b\x38\x43\xf1\x73\xd0\x26\x34\xac\x27\x72\x15\xe7\xe3\x38\x96\xa8\x5c\x57\x5a\x7f\xe6\x4f\xfb\xfd\x3b\x6e\x5d\xc7\x8a\x68\xf0\x7b\x84\x07\x54\x60\x08\x3b\x04\xdf\xb0\x0f\xd6\x5e\x5e\x14\x2b\x7e\x80\x0f\x68\x4d\xc3\x9e\xca\x3d\x6b\x77\xf5\x00\x24\x94\x20\xb1\x80\x2b\x25\x6f\x6c\x17\xbd\xce\x5c\x25\x39\x68\x18\xc0\x50\xe0\x8f\xe5\x23\x67\xab\xd6\xba\xee\x8d\x45\xda\x5d\xad\x64\x25\x3d\x08\xb2\xbe\xb0\xae\xab\xbc\xd5\xd0\x30\x11\x89\xe5\xb2\x43\x0d\x0f\x22\x34\x6c\x9a\xca\x43\xa4\xde\xfa\x8f\xa8\xe1\x10\xe6\x99\xb5\xb7\xd6\x3c\xd7\x7c\xc3\x2c\xcc\xed\x6a\x05\xaa\xed\x76\x89\x19\x64\x0f\x03\x34\xec\x78\x7c\x79\x66\x6d\xf4\xa6\x8a\x11\x55\x35\x4d\x65\xea\xac\xb0\xcd\xbf\xf0\x94\x20\x48\x27\x3e\x0b\x82\x55\x92\x5b\x79\x48\x48\x3a\x4f\xbf\xa6\x8f\x75\xbc\x34\xf3\xdc\xc5\x93\xc6\xd0\x83\x4f\x9a\x2f\xd7\x62\xd5\x6d\xc3\x25\xec\xc2\x88\xb4\x9d\x14\x1a\x4f\x1e\x16\xcc\xb7\x60\x3d\xb1\x42\x5a\x43\x60\x28\xa7\x91\x8f\xba\x85\xc1\xff\x6d\x0e\xe0\x11\xc2\xbd\xf1\x35\xf7\xfe\xf6\x8c\xbd\x20\xd0\xf0\x0b\x4e\xd6\x5e\xc2\x35\xcd\x7b\xef\x31\x80\x4f\xea\x79\x7e\x3f\x4d\xe5\x27\x1b\x68\xa9\x32\xeb\x3e\xf5\x3f\x93\x5e\xd7\xc9\xbf\xec\x7f\x19\xf3\xeb\x43\x4b\xa2\xcf\xc2\x60\x07\x81\x0e\x46\xbd\x3a\x34\x29\xd2\x73\x44\x05\xbc\xad\xf9\xfa\xd0\xdb\xdd\xef\x00\x00\x00\xff\xff\x04\xd6\x4b\x8d\xf3\x02\x00\x00"
+var _contentOpf = "\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x85\x54\x4d\x6b\xe3\x30\x10\xbd\xe7\x57\x18\xdd\x6b\xa7\xbd\x2c\x98\x38\xd0\x6e\xe8\x12\xe8\x42\x21\xdb\x73\x51\xac\x71\x3d\x5b\x47\xf2\xea\xa3\xe9\x12\xf2\xdf\x77\x24\xcb\x5f\x61\x4b\x6e\xd2\x9b\x79\x4f\xa3\xa7\x19\xad\x5a\x5e\xbe\xf3\x37\x48\x3e\x0f\x8d\x34\x05\xab\xad\x6d\xf3\x2c\x3b\x1e\x8f\x29\x8a\xb6\x4a\x95\x7e\xcb\xee\x96\xcb\x6f\x99\x6a\x2b\x96\x38\x89\x7f\x1c\xdc\xa0\x00\x69\xb1\x42\xd0\x05\x7b\x50\xea\x7d\x2b\x58\xf2\x01\xda\xa0\x92\x05\xbb\x4b\x97\x6c\xbd\x58\x1d\xc0\x72\xc1\x2d\xef\x84\x73\x51\x0e\xda\xad\xd3\x4d\xd0\x15\x65\x06\x0d\x1c\x48\xcb\x64\xb7\xe9\x6d\xc6\x62\x2e\x1d\x75\xa5\x10\xd2\x17\x65\x5e\x6a\xe0\x56\xe9\x84\x90\x5c\xab\x06\x0a\xc6\x9d\x65\x61\x5b\x61\x03\x37\x9c\xee\x73\x3a\xa5\xf7\xce\xd6\x4a\x3f\x12\x72\x6f\xce\x67\xb6\x1e\xa0\xf3\x79\x95\x8d\x32\xeb\xc5\xe9\xa4\xb9\x24\x2b\xd2\xef\x4a\x5a\x8d\x7b\x47\x28\x31\xc2\x51\x23\x72\x71\x9c\x97\xeb\x85\xc6\x24\x2f\x06\x52\x74\xe4\xd1\xae\x04\xc5\xe8\x98\xd7\x31\x65\x4d\x06\x14\xec\xe5\x65\xbb\x61\x6b\xa7\x65\xee\x1c\x8a\x9c\x34\x3d\x12\x75\x47\xbe\x97\x3d\xa2\xad\x93\xf4\x11\xb5\xb1\xcf\x6e\xdf\xa0\xa9\x21\x9e\x43\x76\x43\x50\x85\x0f\x22\x14\xac\xf5\xe1\x92\x5b\x7a\x96\x69\x99\x3e\x6d\xa8\xaf\xd7\x7b\xe2\x57\xe5\x0e\x4a\x50\x11\x57\xf4\xfe\x47\x0c\x06\xf7\xa4\x0d\x05\x67\x44\xcf\xb0\x68\x1b\xf0\xd1\x5f\x7e\x11\xc3\x1d\x18\xe2\x6d\xac\x4c\xfb\x9c\xbe\xcc\xfe\xfd\xc6\x60\xc8\x6d\xe8\x0d\x1d\x75\xb4\x4f\x7d\xa2\x75\xcc\x1a\xe0\xc1\xc2\x0d\x98\x52\x63\xeb\x2b\x8b\x75\x8f\xc0\xf4\x7a\x13\x74\x74\x2d\x76\xca\xce\xed\x7f\x43\x69\x63\x97\x98\x6e\x37\x21\xf7\xc8\xa5\xdd\x3b\xe5\x74\x09\x91\x15\xd6\x53\x52\x07\x0c\x96\xfa\x51\x4a\x24\xf7\x7d\x52\xf2\x06\xf7\x1a\x3a\x6f\x5e\x8d\xd2\xd4\xef\xbe\xed\x82\xcf\xbd\x7f\x43\xab\x67\x71\x0e\x2f\xc8\x06\x34\x82\x99\x13\x77\x01\xeb\x38\x43\x91\x01\x7b\x56\x06\xa3\x47\x5f\x6a\xbd\xa2\x14\xf0\x39\x57\xec\xb5\xe2\xbd\xb1\xf2\x73\x45\xbf\xc4\xf6\x40\xaf\x10\x7a\x7b\x2a\xe7\x23\x73\xfe\x3c\x79\xa2\x35\xe5\x1d\x6b\xea\xa1\x06\xfe\xc2\x9e\x86\xca\xf4\xa3\x7c\x21\xd4\x81\xd1\x8f\xf9\x3c\x4e\x67\x30\x5e\x29\x74\xe9\x17\xd3\xb7\xca\xfa\x6f\xcd\x27\xfd\xe4\x12\x2b\x30\xf6\x5e\x8a\x5d\x8b\x12\xfc\x35\xd3\x1f\x34\xbf\xa1\x83\xe3\xc7\xba\x5e\xfc\x03\xff\x41\x7b\xd6\x63\x05\x00\x00"
 
 func contentOpfBytes() ([]byte, error) {
 	return bindataRead(
@@ -88,12 +93,52 @@ func contentOpf() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "content.opf", size: 755, mode: os.FileMode(420), modTime: time.Unix(1533332351, 0)}
+	info := bindataFileInfo{name: "content.opf", size: 1379, mode: os.FileMode(420), modTime: time.Unix(1792308496, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
 
-var _aboutHtml = "\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8c\x54\xc1\x6e\xe3\x36\x10\x3d\x47\x5f\x31\xd5\xa9\x05\x6c\x11\x46\x2e\xcd\x56\x11\xba\x6b\x07\x48\x80\xdd\x36\x28\xb4\xd8\xf6\x48\x89\x23\x93\x08\xc5\xd1\x92\xa3\xc8\x82\xa1\x7f\x2f\x48\x79\x93\x06\x2d\x8a\xf5\x85\x1e\xcf\xbc\x99\xf7\x1e\x87\x2e\x7f\x38\xfc\xbe\xaf\xff\x7a\xbc\x03\xcd\xbd\x85\xc7\xcf\x1f\x3e\x3e\xec\x21\xdf\x0a\xf1\xe5\x7a\x2f\xc4\xa1\x3e\xc0\x9f\xf7\xf5\xa7\x8f\xb0\x2b\x76\x42\xdc\xfd\x96\x67\x70\xf9\xe4\x9a\x79\x78\x27\xc4\x34\x4d\xc5\x74\x5d\x90\x3f\x8a\xfa\x0f\x71\x8a\x6d\x76\xbb\x08\xfc\xf6\xbd\x50\xac\xf2\x2a\x2b\xd3\x80\x53\x6f\x5d\xb8\xfd\x0f\xec\xee\xe6\xe6\x66\x45\xa4\x5a\x94\xaa\xca\xca\x1e\x59\x42\xac\xdd\xe2\xd7\xd1\x3c\xdf\xe6\x7b\x72\x8c\x8e\xb7\xf5\x3c\x60\x0e\xed\x1a\xdd\xe6\x8c\x27\x16\x11\xfb\x0b\xb4\x5a\xfa\x80\x7c\x3b\x72\xb7\xfd\x39\x17\x55\x56\xb2\x61\x8b\x55\x29\xd6\x33\x2b\x45\x6a\x5e\x36\xa4\xe6\x2a\xbb\x2a\xf5\xae\x3a\x9f\x8b\x3a\x26\x97\xa5\x14\x7a\x97\x7e\xbc\xae\x9a\x19\xce\xe7\xe2\xfd\xc8\x9a\x7c\x4a\x5c\x5f\x12\xc3\xd8\x58\x13\x34\x2a\x58\x4b\x1e\x2f\xf1\x3f\xab\xbc\x88\xc7\x50\x95\xd8\x57\xb5\x36\x01\x26\xf2\x4f\x60\x02\xb4\x34\xcc\xde\x1c\x35\xaf\x70\xd6\x08\x32\x8d\x08\x1b\x98\x0c\x6b\x28\x25\x68\x8f\xdd\xab\x45\x5a\x32\x5a\x9c\x65\x2b\x15\xf6\x73\xe1\x90\x85\x71\x0a\x4f\xc5\xa0\x07\xd1\xc9\xaf\x5b\x8d\x76\x10\xbb\x9b\xed\x4b\xeb\xbc\xc2\x53\x6b\xc7\x60\x9e\x11\x94\x09\xec\x4d\x33\xb2\x21\x07\x29\x1b\x4a\x21\x2b\xd0\x68\x5f\x08\x7c\xb9\x8c\x80\xf7\xeb\x0c\x98\xb0\x09\x86\x11\x7e\x1c\x9d\xc5\x10\xc0\x38\x65\x5a\x19\x29\x13\x6b\xf4\x93\x09\xf8\x53\x91\x5d\x25\x61\x77\xdb\x0f\x44\x4f\xd0\x19\x8b\x30\xc9\x00\xad\xc7\x54\x99\xc4\xc8\xb7\x72\xc2\x3b\x21\x8e\x86\xf5\xd8\x14\x2d\xf5\xc2\x9b\x27\xe3\x8e\x2f\x0a\xb7\xd8\x10\x3d\x85\xbc\x62\x22\x9b\x48\x4e\xde\x30\xa3\x8b\x3c\x4b\x81\x7d\xb5\x02\xa2\xa7\x20\x9d\x7a\xd5\x86\x0a\x3a\xf2\xd0\x79\xc4\x22\xbb\x7a\xe8\x60\xa6\x11\x06\x69\x14\xf4\xe4\x70\x4e\x49\x8e\x64\x23\xcb\x0d\x90\x07\x8f\x2d\x9a\x67\x54\x60\x18\x3a\x4f\x3d\x04\xea\x91\x1c\x6e\x12\x54\x7a\x04\x09\xcf\xa6\x65\xd3\x03\x75\xaf\x97\x06\x9d\x97\xa3\x2a\xb2\xab\x03\x5a\x64\x4c\xee\x25\xe5\x91\xce\xaa\x3c\x36\xf0\x40\x93\x4b\x28\x18\x83\x71\xc7\x54\x17\x55\x15\x49\x46\x29\x86\x75\x3b\x6a\x8d\xa0\xa9\xc7\x41\x1e\x31\xce\xf9\xd7\x45\x98\x90\xa0\xf9\xde\xcf\x81\xa5\x85\x7b\x69\xed\x26\x07\xc9\xdf\xb5\x26\x79\xf5\x3f\xc9\x68\xf0\x06\x26\x8d\x3e\x51\x86\x56\x3a\xf0\x28\x55\x1c\x18\x10\x02\x93\x37\x18\xaf\x1e\x64\xdc\x07\x68\x3c\x4d\x01\xfd\xab\xd1\x17\x15\x2f\xab\x5e\x7f\x33\xf8\xcd\x1a\x90\x8b\x4f\xe4\x20\x19\x97\xe5\xf2\x5e\x3e\x07\xf4\x4e\xf6\xb8\x2c\xbf\x9e\xcf\xc5\x3d\x05\x5e\xa3\x0d\xf4\xb2\xd5\xc6\x21\x3c\x1c\x62\xdd\xa7\x35\x7a\x38\x2c\x4b\xf2\xb7\x97\x33\x38\x62\x68\x10\xd8\x4b\x17\x3a\xf4\x1e\x15\x30\xad\x5b\x09\x03\xd2\x60\x2f\xbc\x4a\x91\x9e\x77\x99\xfe\x14\xaa\xec\xef\x00\x00\x00\xff\xff\xc3\x56\x0b\xd6\xe6\x04\x00\x00"
+var _content3Opf = "\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x9d\x55\xdb\x6a\xdc\x30\x10\x7d\xcf\x57\x18\x75\x1f\x6b\x3b\x69\x1f\x0a\x26\x5e\x48\x13\x52\x02\x29\x04\xd2\x3e\x07\xad\x35\xde\x55\x23\x4b\xae\x2e\xd9\x84\x65\xff\xbd\x23\x59\x5a\xdb\xb9\xb5\xf4\xc5\xc8\xe3\x39\x67\xce\xdc\xe4\xd3\x9e\x36\xf7\x74\x0d\xd9\x63\x27\xa4\xa9\xc9\xc6\xda\xbe\x2a\xcb\xed\x76\x5b\x70\xd6\xb7\x85\xd2\xeb\xf2\xd3\xf1\xf1\x97\x52\xf5\x2d\xc9\x9c\xe4\xbf\x1d\xe4\x9c\x81\xb4\xbc\xe5\xa0\x6b\xf2\x55\xa9\xfb\x2b\x46\xb2\x07\xd0\x86\x2b\x59\x93\xcf\xc5\x31\x59\x1e\x9d\x76\x60\x29\xa3\x96\x0e\xc4\x15\x6b\x0e\xdc\xbd\xd3\x22\xf0\xb2\xa6\x04\x01\x1d\x72\x99\xf2\xa4\x38\x29\x3d\x8c\x35\x55\xa3\x81\x5a\xa5\x33\xce\x6a\x12\xcf\x64\xb9\xdb\x15\x67\xce\x6e\x94\xde\xef\x4f\xcb\xd1\x29\x06\xca\x34\xb4\x5c\x02\xea\xff\x90\x10\x59\xaf\x55\x0f\xda\x3e\xd5\xa4\xe5\x02\x72\x6a\x26\x24\x97\x68\x39\x33\x9e\xca\xa3\xff\x89\x44\x2b\x01\x24\x33\xcd\x06\x05\xd7\xa4\xa3\xba\xa9\x34\x08\xef\x85\xc4\xd4\xd9\x44\xb5\xdb\x69\x2a\xb1\x9e\x0b\xfe\x31\x5b\x3c\x64\x55\x9d\x15\xe7\x4a\x5a\xcd\x57\xce\xfb\x62\x4c\xaf\x7e\xb4\x0c\x69\x8e\xef\xbb\xdd\x82\xef\xf7\x5e\xeb\xe2\x21\xe5\x3a\x7e\x7d\x45\xea\x0b\xe8\x7f\x89\x06\xc9\x06\x69\x63\x6f\x83\xb2\xd8\xde\xa5\xd3\xb2\x72\x8e\xb3\x0a\x6b\xf8\xf3\xe7\xd5\x45\x94\x36\x7a\x0f\xbd\xc3\x8e\x03\x4a\xdf\x72\xbb\xc9\x8a\x4b\xae\x8d\xbd\x71\x2b\xc1\xcd\x06\x90\x1e\xa1\xfe\x01\xc2\x40\x78\xb9\x40\xe7\x60\x08\xb1\xcb\x04\x8f\x29\x8e\x59\xb0\xc6\x82\xee\x4c\xd5\x29\xe6\x43\xb1\xd0\xc8\xef\xf1\x65\xd2\x44\x24\xb0\xdc\x0a\x08\xc2\xc3\x29\x78\xfe\xf0\xa7\x18\x20\x58\x5f\x16\x71\x70\x7e\x63\x64\x02\xfe\xc5\xc4\x20\x59\x1f\x53\xd3\xde\x2b\xe5\x99\x06\x74\xfc\x18\x7c\x05\x0e\x85\xc3\x3d\xf3\xae\xd7\x78\x8e\x5e\x07\xf3\x51\x2a\xda\x05\x98\x46\xf3\xde\xe2\x2e\x0d\x0d\x61\xa3\x61\x19\x2a\x38\x54\x6a\x62\x4d\xed\x4b\xa3\x57\xdc\xba\xd5\x2f\x68\x6c\x1c\x36\x33\xbc\x4d\xc0\xc9\x32\x02\x87\xd8\xb7\xca\xe9\x06\x22\x2a\x9c\xa7\xa0\xc1\x30\x62\x78\x8b\x08\xd0\x1c\x7c\x9c\x67\x2d\x5b\x81\x50\x72\x6d\x72\xab\xf2\x46\x09\x81\xc1\x50\x28\x09\x8d\x31\x01\x12\x2a\x7b\x40\xbf\xb1\x86\xd1\x75\xc2\x3b\x92\xe5\xf6\xa9\xc7\xfe\x0e\x2e\xe3\x1c\xc7\x44\x82\xf5\x46\x19\x1e\xeb\xf8\x57\xe2\xb5\x56\xae\xcf\xfb\x88\x20\x29\xef\xd9\x7a\xa4\x49\x0d\x64\x92\xfa\x95\x6a\xa8\xe0\x2b\x0d\xc3\x5c\xdd\x19\xa5\x2d\xc9\xfc\x46\xe2\x56\xd4\xe4\xf9\xec\x90\x32\xa5\x38\x07\x27\x35\x53\x60\x2a\x8d\xc7\xbc\x9f\xd5\x6b\x5c\x77\x5c\x32\x78\x9c\x33\x26\xae\x59\xc7\xaf\xe9\x6c\x43\xa7\x8c\xdb\x0d\x2e\xa3\x80\x27\x58\xe1\x15\x60\x70\x52\x8d\xcd\xd3\x50\xb3\x77\xa9\xfd\x60\x9c\x2b\xfc\x21\x5c\x75\x38\xda\xe1\xae\x98\x2a\xf5\x5f\xe6\xf8\xb9\xf3\x84\xeb\x1d\x3d\x87\x2b\x7a\x46\x34\x18\x63\xa9\xcb\xf4\x0f\x0a\xf7\x05\x95\xbc\x05\x63\xcf\x24\xbb\xed\x71\x0c\xc2\x0d\xf4\x0d\x6f\xb4\x70\x35\xc4\xbf\xe0\xf2\xe8\x0f\x9c\x27\x52\x50\x10\x07\x00\x00"
+
+func content3OpfBytes() ([]byte, error) {
+	return bindataRead(
+		_content3Opf,
+		"content3.opf",
+	)
+}
+
+func content3Opf() (*asset, error) {
+	bytes, err := content3OpfBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "content3.opf", size: 1808, mode: os.FileMode(420), modTime: time.Unix(1792308496, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _navXhtml = "\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x65\x52\xb1\x4e\xc3\x30\x10\xdd\xfb\x15\xc6\x7b\xe3\xb6\x0c\xd0\x28\xe9\x02\x6c\x15\x20\xd1\x85\xd1\x49\xae\xb1\x55\xc7\x8e\xe2\x6b\xd3\xa8\xca\xbf\x73\x76\x28\x82\x32\xdd\xdd\xf3\xcb\x7b\xef\x4e\xc9\xee\x9e\xdf\x9e\x76\x9f\xef\x2f\x4c\x61\x63\x36\xb3\x2c\x14\x76\x6e\x8c\xf5\x39\x57\x88\x6d\x2a\x44\xdf\xf7\x49\x7f\x9f\xb8\xae\x16\xcb\xf5\x7a\x2d\xce\x81\xc3\x27\x52\x0a\xed\xb1\xf8\xc3\xd4\x55\xbb\x8f\xdc\xd5\x62\xf1\x20\x5c\xeb\x39\x33\xd2\xd6\x39\xbf\x5c\x92\x2d\x35\xe3\x18\x3f\x4d\x6f\xc1\xe0\x0d\xb2\xa2\xd2\x00\x4a\x56\x2a\xd9\x79\xc0\x9c\x1f\x71\x3f\x7f\xe4\xe2\x8a\x5b\xd9\x40\xce\x6b\xb0\xd0\x49\x74\x1d\x67\xa5\xb3\x08\x16\xa7\x0c\x9e\x42\xd4\x1a\xd5\xb1\x48\x4a\xd7\x88\x4e\x1f\xb4\xad\x45\xaf\x24\x82\x81\x61\x0e\x85\x73\x07\x1f\xc5\x50\xa3\x81\x0d\xd9\xef\x42\x33\x8e\x99\x98\x90\x59\x66\xb4\x3d\x30\xd5\xc1\x3e\xe7\x1f\x38\x18\xf0\xc2\x93\xd1\x90\x94\x9e\x56\xe9\xc0\xe4\xdc\x47\x58\x01\x20\x67\x38\xb4\x94\x07\xe1\x8c\x22\x10\x82\xb4\x88\x7b\x64\x85\xab\x06\x9a\xac\x3c\xb1\x70\xa4\xf4\x9b\xe9\x4a\xce\x74\x35\x35\x61\xe7\xe5\x9f\x10\x34\xce\x68\x7e\x95\xa7\xad\xf6\x38\x8e\xa4\x46\x02\xff\x65\xe8\x7a\x55\x23\x3b\x5a\x26\x8a\xfd\x1a\x95\xae\x2a\xb0\x74\x8e\x58\xa3\xc5\x6a\xb3\xbd\xbe\x93\xc3\x2a\x3a\xfc\x20\x37\x3e\x22\xc6\x26\x5a\xfc\x1d\xbe\x00\xb7\xc5\xed\x4e\x1f\x02\x00\x00"
+
+func navXhtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_navXhtml,
+		"nav.xhtml",
+	)
+}
+
+func navXhtml() (*asset, error) {
+	bytes, err := navXhtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "nav.xhtml", size: 543, mode: os.FileMode(420), modTime: time.Unix(1792308496, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _aboutHtml = "\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x6d\x53\xdf\x6f\x9b\x30\x10\x7e\x6e\xfe\x8a\x1b\x4f\x9b\x14\xb0\xa2\xbc\xac\x1b\x41\x6a\x93\x4a\x8d\xd4\x6e\x55\x47\xd5\xed\xd1\xc0\x11\x5b\x05\x4c\x6d\x53\x82\x22\xfe\xf7\x9d\x4d\xda\x74\x5a\x79\x31\xe7\xbb\xef\xbe\xef\x7e\x38\xfe\xb4\xf9\xb9\x4e\xff\xdc\x5d\x81\xb0\x75\x05\x77\x0f\x97\x37\xdb\x35\x04\x21\x63\x8f\xcb\x35\x63\x9b\x74\x03\xbf\xaf\xd3\xdb\x1b\x58\x44\x0b\xc6\xae\x7e\x04\x33\x38\x7e\x81\xb0\xb6\xfd\xc6\x58\xdf\xf7\x51\xbf\x8c\x94\xde\xb1\xf4\x9e\xed\x5d\x9a\xc5\xc2\x01\x5f\xff\xa3\xc2\x16\x41\x32\x8b\x3d\xc1\xbe\xae\x1a\xb3\xfa\x00\xbb\x38\x3f\x3f\x9f\x10\x3e\x16\x79\x41\x47\x8d\x96\x83\x8b\x0d\xf1\xb9\x93\x2f\xab\x60\xad\x1a\x8b\x8d\x0d\xd3\xa1\xc5\x00\xf2\xc9\x5a\x05\x16\xf7\x96\x39\xec\x77\xc8\x05\xd7\x06\xed\xaa\xb3\x65\xf8\x35\x60\x94\xc4\x4a\x5b\x61\x12\xb3\xe9\x9c\xc5\xcc\x27\x8f\x33\x55\x0c\xc9\xec\x2c\x16\x8b\xe4\x70\x88\x52\xe7\x1c\x47\x72\x2e\xfc\xe5\x32\xc9\x06\xa0\xfb\x8b\xce\x0a\xa5\xbd\x63\x79\x74\xb4\x5d\x56\x49\x23\xb0\x80\x29\xe4\xee\x68\xbf\x8f\xd2\xcc\x1d\x6d\x12\x63\x9d\xa4\x42\x1a\xe8\x95\x7e\x02\x3a\x73\xd5\x0e\x5a\xee\x84\x9d\xe0\x56\x20\x70\x4f\x61\xe6\xd0\x4b\x2b\x20\xa6\x82\x35\x96\xab\x80\x32\xff\x92\x16\x1f\xee\x6f\xc6\x51\x36\x05\xee\xa3\x56\xb4\xac\xe4\xcf\xa1\xc0\xaa\xa5\x7e\x85\x6f\xb9\x82\x04\xf7\x79\xd5\x19\xf9\x82\x50\x48\x63\xb5\xcc\x3a\x2b\x55\x03\xde\x6b\x62\xc6\x13\x20\xd0\x1b\xe3\xa3\xe0\x16\x2b\x1c\xe0\x22\xe7\x05\xd6\x03\xf4\x98\x19\xe2\x82\xcf\x5d\x53\xa1\x31\x40\x7c\x32\xe7\x4e\xa3\xa2\x78\xdd\x4b\x83\x5f\xa2\xd9\x99\xaf\xe4\x2a\xbc\x54\xea\x09\x4a\x59\x21\xf4\x9c\x2a\xd2\xe8\x23\xbd\x7a\x7e\xd2\xef\xc6\x66\x68\xc6\x3b\xba\xef\xb2\x28\x57\x35\xd3\xf2\x49\x36\x3b\xd6\x1f\xe9\x43\xcc\x28\x93\x09\x12\xab\x54\xe5\x45\xf6\x5a\x5a\x9a\xa8\xd3\x19\x33\x6a\xdd\x04\x70\x4d\x04\xde\x14\xa7\xda\x88\xae\x54\x1a\x4a\x8d\x48\xb2\xb6\x25\x0c\xaa\x83\x96\xcb\x02\x6a\xd5\x50\x5d\xce\x69\x9d\x58\xa7\x72\x0e\x64\x69\xcc\x91\xda\x53\x80\xb4\x04\x53\x35\x18\x55\x23\xc5\xce\x3d\x94\x6b\x1a\x03\xbc\xc8\xdc\xca\x1a\x54\x79\x9a\x12\xc5\xf2\xae\x20\x8e\x0d\x09\xa6\x06\xb9\xee\xf9\xca\x9d\x9c\xa9\x72\x97\x40\x83\xea\x1b\x8f\x02\x9a\x42\xb3\xf3\x71\xae\xaa\xc8\x97\x11\xb3\x76\x5a\x87\x94\xae\x05\x11\xb7\x7c\x87\x8e\xe7\xbf\x41\x90\x64\x07\x0d\xd6\x7a\x30\x96\x57\x70\xcd\xab\x6a\x1e\x00\xb7\x1f\xef\x45\x90\xbc\xb7\x5c\x0b\x69\x8b\x68\x60\x5e\x14\xe4\x9c\x36\x80\x76\xdd\xa5\x34\x08\xc6\x2a\x2d\xd1\x0d\x97\x6a\xa5\x89\x43\xa6\x55\x6f\x50\x9f\x5a\x79\xd4\xf9\xb6\xbd\xe9\x6b\x0b\xff\x19\x34\xed\x15\xb1\x6e\xe8\x7f\x1c\x8f\x4f\x60\xed\x5c\xee\x99\x1c\x0e\x7e\x0d\xa2\x5b\x9e\x0b\xd9\xe0\x76\x33\x8e\x73\xa8\x27\x03\xb6\x1b\x17\xeb\x82\xb0\x29\x08\xeb\x7a\x58\xf3\x01\x1a\x65\x21\xa3\x7e\x69\xde\x98\x12\xb5\x26\x0e\xab\xa6\xcd\x83\x16\x55\x5b\x1d\x95\xc5\xcc\xbf\xd9\xd8\xbf\xf4\x64\xf6\x17\xdc\x37\x91\xbd\xbb\x04\x00\x00"
 
 func aboutHtmlBytes() ([]byte, error) {
 	return bindataRead(
@@ -108,7 +153,47 @@ func aboutHtml() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "about.html", size: 1254, mode: os.FileMode(420), modTime: time.Unix(1533332606, 0)}
+	info := bindataFileInfo{name: "about.html", size: 1211, mode: os.FileMode(420), modTime: time.Unix(1792309258, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _attributionHtml = "\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x75\x90\xc1\x0a\xc3\x20\x0c\x86\xef\x7d\x0a\xf1\xbe\x4a\xef\x56\xd8\x65\xa7\x1d\xc6\xe8\x1e\xc0\x56\x87\x01\xa9\xa2\x71\x63\x88\xef\x3e\x2b\xf4\xb4\xee\xf6\x93\xe4\xfb\x48\xc2\x15\xbc\xc8\x62\x65\x8c\x23\x95\x88\x01\xe6\x84\xe0\x56\x2a\x3a\x6e\x86\x83\xc6\x09\x01\xad\xa6\x22\xe7\x7e\xda\x52\x29\x9c\x99\x41\x74\x39\xbf\x01\x0d\xe9\xcf\x09\x8d\x0b\xb5\xea\x8f\x60\xd9\xba\x54\xcc\x1f\x52\x05\x1b\xeb\x37\x54\xaf\xaa\x94\xdd\x70\x4b\xb3\x85\x68\xb4\xfa\x27\x51\x12\xeb\x02\x17\x08\x11\x89\xdf\x87\x7f\x7d\xc7\x70\x74\x29\x2c\x15\xe7\x92\x98\xa0\x9f\x23\xad\xdc\xe3\x7e\x2d\xa5\x9d\xd4\x12\x67\x52\x34\x11\x67\xf5\x39\xa2\xfb\x02\x59\x75\xe9\x6a\x23\x01\x00\x00"
+
+func attributionHtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_attributionHtml,
+		"attribution.html",
+	)
+}
+
+func attributionHtml() (*asset, error) {
+	bytes, err := attributionHtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "attribution.html", size: 291, mode: os.FileMode(420), modTime: time.Unix(1792308397, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _bookHtml = "\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x4d\x52\xc1\x8e\xdb\x20\x10\xbd\xfb\x2b\x66\xdd\xdb\x4a\x36\xea\x56\x95\xd2\xd4\xc9\xa1\xde\x1e\x2a\x55\xda\x4a\xc9\xa5\x47\x6c\x88\x41\x8b\xc1\x82\x49\x76\xa3\xc8\xff\xde\x01\xec\x4d\x7d\x30\xf8\x31\xbc\xf7\x3c\x6f\x9a\x87\xe7\x97\xf6\xf8\xf7\xcf\x4f\x50\x38\x9a\x7d\xd1\xc4\x05\x0c\xb7\xc3\xae\xbc\xdd\xea\xdf\xb4\x99\xe7\x32\xe2\x92\x0b\x5a\x46\x89\x1c\x7a\xc5\x7d\x90\xb8\x2b\xcf\x78\xaa\x36\x25\x5b\x71\xcb\x47\xb9\x2b\x07\x69\xa5\xe7\xe8\x7c\x09\xbd\xb3\x28\x2d\x15\x2a\xc4\x29\x6c\x19\x1b\x34\xaa\x73\x57\xf7\x6e\x64\x5e\xbf\x6a\x3b\xb0\x37\xc5\x51\x1a\x79\xad\x64\xe7\xdc\x6b\x48\x64\xa8\xd1\xc8\x3d\xc9\x1f\xe3\x66\x9e\x1b\x96\x91\xa2\x09\x78\x8d\x2b\x1d\xb5\x87\xc3\x3c\x17\x0d\x5b\x90\xf5\x84\x3d\x42\x20\x5a\x23\xe1\xa4\xe9\x65\xf8\xd5\x9d\x11\x1e\x59\x11\x64\x8f\xda\xd9\x7a\xe2\x1e\xe1\x56\x00\x3d\x9d\xf3\x42\xfa\x0a\xdd\xb4\x85\xcf\xd3\x3b\x04\x67\xb4\x80\x4f\x9b\xcd\xe6\x7b\x3a\x1f\xb9\x1f\xb4\xcd\xe7\x5f\xe4\x98\xc1\x89\x0b\x41\x02\xcb\xad\x88\xce\x85\xe5\x97\x1a\x5d\x0f\xce\x2c\xcc\x46\x07\xac\x92\xa3\x2d\x58\x67\x65\x2c\x12\xfa\x42\xff\x7d\x21\x41\x3d\xf2\x41\x82\x1e\x87\xa5\x5a\x49\x3d\x28\xdc\x02\x3f\xa3\x5b\x95\xdf\xab\x15\xfd\xf6\xf5\xa2\x32\xfa\xa6\x05\xaa\x5c\x06\x0f\x7a\x9c\x9c\x47\x6e\x31\x72\xdf\xdb\xc0\x52\x4e\x4d\xe7\xc4\x35\xb6\x49\x9f\xa0\x6e\xa3\x28\x35\x91\x0c\x40\x6f\x78\x08\xbb\xf2\x3f\x1f\x25\x68\x91\xa2\x4e\x65\xbf\x9e\x63\xda\x4d\xf4\x16\x7c\x7f\xc7\x09\x05\x6e\x30\x01\x4b\x28\x94\x54\xc3\x88\x33\xca\x48\x2b\x48\x80\xda\xb0\x0a\x50\x37\x32\x71\xdc\xec\x1b\xf5\xb4\x3f\xf2\x8e\xf2\x70\x27\x68\xf3\x4c\x04\xb2\xfa\x94\x92\x3c\xbe\xb4\x29\x49\xba\x1e\xbf\x3d\x8d\x9c\x84\xfa\x90\xf3\x0a\xc4\xbb\x44\xb7\x72\xc7\x04\x3f\x5c\x67\xc3\x91\xe6\x07\xfd\x72\x9e\x88\x5c\x7e\xf7\xc5\x52\x37\x48\x2f\x4d\xf8\x3f\x3a\x8d\x89\xf1\xf2\x02\x00\x00"
+
+func bookHtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_bookHtml,
+		"book.html",
+	)
+}
+
+func bookHtml() (*asset, error) {
+	bytes, err := bookHtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "book.html", size: 754, mode: os.FileMode(420), modTime: time.Unix(1792311652, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
@@ -133,7 +218,27 @@ func coverHtml() (*asset, error) {
 	return a, nil
 }
 
-var _partHtml = "\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x6c\x8f\xc1\x8e\x9b\x30\x10\x86\xef\x3c\x85\xeb\x3b\x1e\xa1\x5c\x9a\xd4\x70\x08\x49\xd5\x4a\x69\x1b\x35\xac\x76\xf7\x48\x60\x02\x28\x06\xb3\xf6\x64\x09\x42\xbc\xfb\xca\x4e\x22\xed\x61\x7d\x1a\x8f\xfe\xef\xb3\x7f\xf9\x6d\xf3\x2f\xcd\x5e\xf7\x5b\x56\x53\xab\xd8\xfe\x69\xbd\xfb\x9d\x32\x1e\x02\x3c\x2f\x52\x80\x4d\xb6\x61\x2f\xbf\xb2\x3f\x3b\x16\x89\x08\x60\xfb\x97\x07\xec\x7e\x78\x4d\xd4\xaf\x00\x86\x61\x10\xc3\x42\x68\x53\x41\xf6\x1f\xae\x4e\x13\x45\x0e\x7c\xcc\xa2\xa4\x92\x27\x81\xf4\x0f\x5c\x5b\xd5\xd9\xf8\x0b\x36\x5a\x2e\x97\x37\xc2\x67\x31\x2f\x93\x40\xb6\x48\x39\x73\xd9\x10\xdf\x2e\xcd\x7b\xcc\x53\xdd\x11\x76\x14\x66\x63\x8f\x9c\x15\xb7\x5b\xcc\x09\xaf\x04\x8e\xfd\xc1\x8a\x3a\x37\x16\x29\xbe\xd0\x29\xfc\xce\xe1\x21\xe9\xf2\x16\x63\x5e\x61\x87\x26\x27\x6d\x3e\xb1\x4e\x6f\x57\x00\x55\x43\xf5\xe5\x28\x0a\xdd\x82\x69\xce\x4d\x57\xc1\x50\xe7\x84\x0a\xc7\x10\x8f\x5a\x9f\xad\x97\x51\x43\x0a\x93\x69\x12\x99\x1b\xe6\x59\xc2\x6d\x13\x48\xd5\x74\x67\x56\x1b\x3c\xc5\x5c\x08\x38\xd0\xa8\xd0\x82\x25\x6d\x46\x51\x58\xcb\x99\x41\x15\x73\xeb\xd7\x35\x22\x71\x46\x63\x8f\xf7\xaf\xfb\x80\xd3\x83\x2f\x2e\x8f\xba\x1c\x93\x60\x9a\xc4\xc1\xf1\x6b\x5d\x8e\x3f\xb5\xc9\xb0\xed\x55\x4e\x38\xcf\x81\x04\x9f\x90\xbe\x73\x12\x7c\x04\x00\x00\xff\xff\xee\x44\xb4\xe3\xc5\x01\x00\x00"
+var _coverImageHtml = "\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x6d\x4f\x41\x4e\xc3\x30\x10\xbc\xe7\x15\x8b\xef\xf1\x2a\xea\x85\x82\x93\x03\x69\x25\x2a\x15\xa8\x20\x08\x38\x86\x64\xdb\x44\x38\x49\xb1\xb7\x4d\xa3\xaa\x7f\xc7\x4e\xcb\xad\xbe\xd8\x33\xde\x99\xd9\x51\x37\xb3\x97\x34\xfb\x5a\xcd\xa1\xe2\x46\xc3\xea\xfd\x61\xb9\x48\x41\x84\x88\x1f\x93\x14\x71\x96\xcd\xe0\xf3\x31\x7b\x5a\x42\x24\x23\xc4\xf9\xb3\x08\xe0\x72\x44\xc5\xbc\xbd\x43\xec\xfb\x5e\xf6\x13\xd9\x99\x0d\x66\xaf\x78\xf0\x36\x51\xe4\x85\xff\x6f\x59\x72\x29\x92\x40\x8d\x01\x87\x46\xb7\x36\xbe\xa2\x8d\xa6\xd3\xe9\x59\x31\xce\x52\x5e\xba\xab\x21\xce\xc1\xcf\x86\xf4\xbb\xab\xf7\xb1\x48\xbb\x96\xa9\xe5\x30\x1b\xb6\x24\xa0\x38\xa3\x58\x30\x1d\x18\xbd\xf6\x1e\x8a\x2a\x37\x96\x38\xde\xf1\x3a\xbc\x15\xe8\x4c\xb8\x66\x4d\x49\xda\xed\xc9\x28\x3c\x83\x40\xe9\xba\xfd\x81\xca\xd0\x3a\x16\x52\xe2\x1b\x0f\x9a\x2c\x5a\xee\xcc\x20\x0b\x6b\x05\x18\xd2\xb1\xb0\x23\x5d\x11\xb1\x00\x76\x89\x97\x20\x3f\xe0\x8d\x71\xdc\x52\x7d\x77\xe5\x00\x85\xce\xad\xeb\x55\xf8\x94\xb0\x6e\xf2\x0d\xf9\x1a\x65\xbd\xbf\xfa\xa3\xea\x66\x03\xd6\x14\xb1\x38\x1e\xe5\xc2\x73\xa7\x93\x80\x5c\xf3\x48\x64\x7e\x47\x47\x60\xa2\xd0\x39\xf8\x24\x9f\xe1\x90\xaf\x98\x04\x7f\x1a\xe0\xd2\x61\xb4\x01\x00\x00"
+
+func coverImageHtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_coverImageHtml,
+		"cover-image.html",
+	)
+}
+
+func coverImageHtml() (*asset, error) {
+	bytes, err := coverImageHtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "cover-image.html", size: 436, mode: os.FileMode(420), modTime: time.Unix(1792307253, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _partHtml = "\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x6d\x90\x4d\x4f\xc3\x30\x0c\x86\xef\xfd\x15\x21\xf7\xc6\xaa\x76\x61\xd0\x56\x62\xdd\x10\x48\x03\x26\x28\x02\x8e\xfd\xf0\xda\x68\x6d\x33\x12\x97\xae\x9a\xfa\xdf\x49\xbb\x4d\xe2\x40\x2e\xb1\x1d\x3f\xef\x6b\xc7\xbf\x5a\xbe\x44\xf1\xd7\x66\xc5\x4a\xaa\x2b\xb6\x79\x5f\xac\x1f\x23\xc6\x5d\x80\x8f\x59\x04\xb0\x8c\x97\xec\xf3\x21\x7e\x5a\x33\x4f\x78\x00\xab\x67\xee\xb0\xf3\xe1\x25\xd1\xfe\x06\xa0\xeb\x3a\xd1\xcd\x84\xd2\x05\xc4\xaf\x70\x18\x65\x3c\x6f\x04\x2f\xb1\xc8\x29\xe7\xa1\xe3\x4f\x06\x87\xba\x6a\x4c\xf0\x0f\xeb\xcd\xe7\xf3\x13\x31\xf5\x62\x92\xdb\xab\x46\x4a\xd8\xd8\xeb\xe2\x77\x2b\x7f\x02\x1e\xa9\x86\xb0\x21\x37\xee\xf7\xc8\x59\x76\xca\x02\x4e\x78\x20\x18\xd9\x5b\x96\x95\x89\x36\x48\x41\x4b\x5b\xf7\x9a\xc3\x45\xa4\x49\x6a\x0c\x78\x81\x0d\xea\x84\x94\xfe\xc3\x8e\xf2\xc6\xce\x52\x48\x2a\xdb\x54\x64\xaa\x06\x2d\x77\xb2\x29\xa0\x2b\x13\xc2\x0a\x7b\x17\x53\xa5\x76\x66\x12\x23\x49\x15\x86\xc7\xa3\x88\xc7\x60\x18\x7c\x38\x55\x1c\xbf\x92\xcd\x8e\x95\x1a\xb7\x01\x17\x02\xde\xa8\xaf\xd0\x80\xb1\x5e\xbd\xc8\x8c\xe1\x4c\x63\x15\x70\x33\x95\x4b\x44\xe2\x8c\xec\x0e\xe7\xd1\xa7\x86\x51\x1e\xa6\xc5\xfd\x54\xe5\x7d\xe8\x58\x97\x3b\x22\x2d\xd3\x96\xa4\x6a\x86\xc1\xe6\x6f\xa3\xde\xc2\xbe\xde\x2b\x1d\x63\xbd\xaf\xec\x84\xc3\x60\xb9\x89\xf0\xa7\x3f\x08\x9d\x5f\x5c\xc8\xb1\xf0\xd5\x01\x00\x00"
 
 func partHtmlBytes() ([]byte, error) {
 	return bindataRead(
@@ -148,12 +253,12 @@ func partHtml() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "part.html", size: 453, mode: os.FileMode(420), modTime: time.Unix(1533325117, 0)}
+	info := bindataFileInfo{name: "part.html", size: 469, mode: os.FileMode(420), modTime: time.Unix(1792308397, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
 
-var _storyCss = "\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x9c\x54\x4d\x8f\xdb\x36\x10\x3d\x47\xbf\x62\x9a\xc5\x02\xad\x61\xcb\x8a\xb7\xab\x64\x95\x63\x0a\x17\x41\x91\x6d\x0f\x05\x82\x1e\x47\xe2\x48\x62\x96\x22\x89\x21\xbd\xb6\x13\xf8\xbf\x17\x14\x25\x7f\x6f\x13\xd4\x17\x43\x6f\xc8\x37\x1f\xef\x0d\xe7\x13\xa8\x78\xeb\x3c\xaa\x16\x95\x02\xe7\xb7\x8a\xdc\x14\x50\xa0\xf5\x24\xa0\x36\x0c\x54\x1a\xf3\x04\x4c\x28\x88\x1d\x4c\xe6\xc9\x7c\x02\x9d\x71\x5e\x6d\xa7\x50\x19\x65\xd8\x41\x8b\xcf\x04\x25\x91\x06\xa6\xce\x3c\x93\x00\xc3\x80\xe2\xcb\xca\x05\x92\xc9\x3c\x49\x4a\x23\xb6\xf0\x2d\x01\x00\xe8\x70\x33\x5b\x4b\xe1\xdb\xe2\x3e\xa3\xee\xfd\x80\x71\x23\xf5\x4c\x51\xed\x0b\x5c\x79\x73\x82\xb2\x6c\xda\x11\xde\x25\x76\xa0\xf1\xb4\xf1\x33\xa9\x05\x69\x5f\x40\x7a\x2f\x75\x08\xca\xae\x19\xc2\x31\x03\xbc\xc9\xb2\x5b\xf8\x49\x76\xd6\xb0\x47\xed\x23\x6f\x4b\x3d\x23\x04\xca\x8b\x60\x4c\x1a\x6e\xda\x0d\x64\x81\x34\x49\xd5\x96\x65\xe5\xa6\x30\x9f\xc0\x1f\xb8\x15\x08\x93\x39\xa4\x82\x09\xbb\x7d\x4f\x87\xfa\x21\x8b\xd5\x5c\x74\x70\x1c\x38\xa9\xfe\x1c\x47\x25\x1b\x5d\x40\x60\xeb\xf3\x1f\xa5\x4d\x4e\xd2\xd6\x46\xfb\x59\xaf\x59\x01\xd2\xa3\x92\x55\xac\x77\xdd\xa2\x97\xf5\x4c\x48\x57\x29\x94\x1d\x31\x7c\x4b\x5e\x1d\xe6\x0e\xef\xb2\xdb\xf7\xc9\xab\x78\x5b\x7e\xa5\x01\x08\x37\x4b\xf9\xb5\x42\x16\xff\xc1\x1f\xf0\x12\xab\xa7\x86\xcd\x4a\x8b\x59\xef\x80\x02\x6e\x84\x10\x31\x36\x02\x8b\xc5\xe2\xb2\xa5\x8a\xb4\x27\x8e\xf8\x33\xb1\x97\x15\xaa\x31\x56\xa2\x23\x25\x35\x9d\xca\x70\x6f\x37\x11\xb0\x28\x84\xd4\xcd\x11\x52\x1a\x16\xc4\x61\x7c\x6f\xa4\x06\x67\x94\x14\x70\xb3\x5c\x66\x59\x96\x5d\x94\x69\x8d\x93\x5e\x9a\xb3\x12\x2a\x45\xc8\x05\x94\xc6\xb7\x11\xa8\x95\x41\x5f\x80\x36\xa1\x8c\x5d\x92\x3e\x92\x1f\x46\x71\xa5\xe5\xbb\xbb\x2c\x5b\x2e\x4f\x6b\x59\xd8\x0d\x08\xb3\x2a\x15\xc1\x4d\x88\x8e\xf1\xf1\xce\x72\x19\xb1\x5d\x92\xb6\x84\x42\xf4\xd2\xbc\x34\xa5\x28\x3d\x69\x01\x4c\xcf\x92\xd6\x24\x86\x05\xed\x57\x2a\xfd\x4c\x25\x86\x11\x2a\x1a\x38\xce\x93\xbc\x50\x77\x96\xe5\xf9\x38\xa2\xc1\xa0\xde\xd8\xb8\x0e\xd7\x6c\xfb\xee\xf6\x72\x49\xf7\xe0\xd8\xb8\x6f\x0f\x1a\x3c\xf4\xbf\x68\xa8\xbf\x3e\xb8\x8a\xc3\xcb\x70\xe4\xa8\x1a\x3b\xa9\xb6\x05\xbc\xfe\x60\x56\x2c\x89\xe1\x91\xd6\xaf\xa7\x30\x7c\x4d\xa1\x33\xda\x38\x8b\x15\x5d\x9f\xdd\x8b\x6d\x1d\x94\x3f\x29\x35\xfd\xf5\xfa\x3a\x1e\xf0\xbd\xb7\x82\x93\x2e\x05\x1d\xda\xc2\x1a\xeb\xb7\xd5\x91\xa9\x05\x55\x86\x31\xda\x6a\x6f\x99\xa5\x64\x5a\x1b\x7e\x72\x97\xc6\x91\x1d\x36\x54\xc0\x8a\xd5\xcf\x69\x3a\xff\x18\xbe\xdc\xbc\x1e\xce\x2f\xde\xa6\x8d\xac\x7f\xf9\xae\x73\x8f\xdd\x91\x3e\xd2\xda\x55\x4a\x5a\x2b\x75\x73\xfa\x14\x0d\x3d\xde\x5d\xd3\xee\xee\x07\xb4\x0b\xad\x7c\x46\xd6\x52\x37\xff\x90\x52\x66\x7d\xe6\xb1\x87\xfc\x21\xbf\xb2\x67\x63\x18\x29\x8a\xb1\x4b\xd2\x4f\x1f\x7f\x1b\x47\x31\xe4\x1b\x52\xe5\x79\x1e\x0e\x7c\x3a\x1c\xf8\x3f\xee\xb8\x92\x3c\xcf\x8f\x8c\x72\x9e\x73\xbf\x7f\x7f\xff\xf9\xf8\xfb\x0f\x6a\x64\xd1\x12\xa7\x5f\x6c\x73\x29\x0f\x93\xa5\xf0\x64\xc4\xff\x9e\xd8\x22\x57\x6d\x47\xfa\xca\xd3\xf1\x5d\xf6\x5d\x92\xb2\x95\x95\x5f\xf1\xb8\xd4\xc3\x93\xd4\xeb\x19\xe2\xff\x06\x00\x00\xff\xff\x06\xec\x44\x34\xb2\x07\x00\x00"
+var _storyCss = "\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x9d\x56\x5b\x6f\xdb\x36\x14\x7e\xae\x7e\x05\xd7\x20\x40\x17\xd8\xb2\x6c\x2f\x4e\xe2\x74\x7b\xe9\xe0\xa1\x18\x9a\xee\x61\x40\xb1\x47\x4a\x3c\xb2\x58\x53\xa2\x40\xd1\xb7\x0e\xf9\xef\x3b\xbc\x48\x96\x2c\x39\xed\xa2\x17\xc3\x87\xdf\xb9\x5f\x27\x37\x24\x51\xc7\x4a\x53\x91\x51\x21\x48\xa5\x8f\x02\xaa\x11\xa1\x8c\x96\x1a\x18\x49\xa5\x22\x10\x4b\xb9\x21\x0a\x28\x03\x55\x91\x9b\x49\x30\xb9\x21\xb9\xac\xb4\x38\x8e\x48\x22\x85\x44\x62\x46\x77\x40\x62\x80\x02\x61\xb9\xdc\x21\x23\xf2\x51\xf6\x75\x5b\x19\x21\xc8\x12\xc4\x92\x1d\xc9\xbf\x01\xc1\x2f\xa7\x87\xf1\x9e\x33\x9d\x2d\x6f\x23\xc8\x1f\x3d\x4d\xad\x79\x31\x16\x90\xea\x25\xdd\x6a\xd9\xa1\x2a\xbe\xce\x6a\xf2\x73\x50\x7a\x31\x1a\x0e\x7a\xcc\x0b\x06\x85\x5e\x92\xf0\x96\x17\xe6\x91\xe7\x6b\xff\xec\x34\x90\x69\x14\x5d\x93\x9f\x78\x5e\x4a\xa5\x69\xa1\x9d\xdc\x0c\xac\x44\x62\x44\xf6\x1e\x9d\x52\xc3\x59\x1e\x48\x64\x84\x06\xa1\x38\x2a\x9e\x60\x58\xd0\xf3\x3f\xe9\x91\x51\x74\x89\x84\x0c\x43\x92\x37\x3e\x9d\xec\x27\x91\xb3\xa6\xe7\x41\xfb\xa1\x63\xfd\x39\x9d\x0a\xbe\x46\x0b\x8c\x34\xab\xbf\xa5\x36\xe8\xa8\x4d\x65\xa1\xc7\x36\x67\x4b\xc2\x31\x87\x3c\x71\xf6\xee\x33\xaa\x79\x3a\x66\xbc\x4a\x04\xe5\x39\x28\xc4\xbf\x39\xc5\x9d\xdc\x47\xd7\x8f\xc1\x1b\xc7\xcd\xbf\x81\x27\x18\xce\x98\x7f\x4b\xa8\x62\x2f\xc8\x37\xf4\x98\x26\x9b\xb5\x92\xdb\x82\x8d\x6d\x05\x2c\xc9\x15\x63\xcc\xbd\xd5\x84\xd9\x6c\xd6\x77\x29\x41\x7f\x41\x39\xfa\x0e\x94\xe6\x09\x15\xf5\x5b\x4c\x2b\x10\xbc\x80\x6e\x1a\x6e\xcb\x83\x23\x94\x94\x31\x5e\xac\x5b\x94\x58\x2a\x2c\x49\x13\xbe\x29\x2f\x48\x25\x05\x67\xe4\x6a\xb5\x8a\xf0\xeb\x99\x59\xca\x8a\x6b\x2e\xcf\x4c\x48\x04\x50\x14\x10\x4b\x9d\x39\x42\x2a\x24\xc5\x84\x14\xd2\x98\xf1\x1c\x84\x4f\xa0\x7d\x28\x06\x5c\x9e\xcf\xa3\x68\xb5\xea\xda\x32\xc3\xa2\x61\x72\x1b\x0b\x20\x57\xe6\xb5\x7e\xaf\x79\x56\x2b\x47\x43\xd9\x19\x76\x14\xb3\xa9\xb9\x14\x25\x97\x7a\x01\x6b\x9a\x1c\x4d\x40\x36\xdb\xd2\xb4\x5c\x61\x22\x67\x9a\x53\xc9\x9c\xbc\x77\xe0\xdf\x46\xe4\xbd\xc9\x16\xfe\x5a\x21\xbf\x12\x5a\x30\xc2\x0b\x13\x50\xdf\xd5\xb6\x7a\x1c\xfa\x05\x9d\xfd\xe2\xb4\xc6\x5a\x94\xad\xef\x01\xde\xba\x50\x3d\xca\x56\xfb\x00\xcc\xd2\x5b\x38\x33\x1e\x78\x7a\x1c\x40\xfa\x17\x8b\xb5\x29\x69\x6b\xf6\x39\x72\x4a\xfb\x4d\x36\x35\x33\xa5\xe1\x6b\xdb\xe2\x19\xbd\x19\xbd\xb6\x6d\x18\x4d\xd1\x1f\xc6\x55\x6e\x06\x62\xbb\x0f\x6c\xa7\x44\xe1\x62\xde\x06\x5e\x84\xdd\x77\x60\x02\x35\x41\x1f\x36\x0d\xa7\x77\x5d\xb5\x17\x81\xb7\x1d\xdc\x45\xe0\xcc\xc3\x62\x29\x3a\x5d\xbc\xf7\x33\xcf\xd0\x2d\xc0\xf5\xf3\x8b\x83\x24\xc4\x6a\x07\x65\x4b\xa8\x95\x23\x06\x89\x54\xd4\x35\x53\x03\xb0\xf0\x4a\x2b\xbe\xb9\x88\x35\xb0\xb1\xce\xb0\x87\xd6\x99\x85\xe7\xb2\x90\x55\x49\x93\x8e\x17\x29\xcd\xb9\x38\x2e\xc9\xdb\x0f\x72\xab\x38\x96\xea\x13\xec\xdf\x8e\x88\xff\x37\x22\x0d\x93\xdd\x05\x61\xb5\x8d\x4d\x1f\x0d\xed\x84\xa8\x93\x63\x2d\xcb\x26\xc5\xa6\xab\x12\x5c\x53\x0a\x87\x0a\xc6\x10\xfb\xc2\xac\xa7\xd0\x92\xc6\x3c\xa7\x4d\x5c\xeb\x21\x14\x9d\x8d\x20\xdb\x13\x8c\xef\x06\x58\xea\xdd\x62\x16\xcf\xe3\x0b\x9d\x8d\x26\x9c\x36\x0f\xc1\x3d\x64\x24\x29\xce\x80\xe8\x0c\x88\xd9\x63\x6a\x8b\x43\x84\xc6\x48\x5f\x92\x94\x6b\x4b\xdf\x67\x12\x89\xce\x76\x59\x58\x52\xed\xc2\xb9\x39\xbd\x55\x38\xb8\xed\xda\xe6\x0e\xac\xc2\x66\x63\x9c\xdc\x31\xc4\xbe\x93\xad\x48\xb9\xf8\x52\x8d\xc5\x10\x6f\x4d\xea\x49\x66\x6f\x87\x11\xa9\x00\xda\xf4\x30\xd3\xb9\xa8\x4d\x6f\xe3\x3b\x3b\x15\x87\xb3\x96\xb9\x2f\xec\xcb\xf1\xcc\xa6\x6d\x11\x63\xcd\xb5\x80\x61\x41\x51\xe8\x7b\xa4\xec\x70\x60\x78\x32\xa9\xbe\xb3\xf8\x3a\x6e\x76\xf9\x19\xd5\x30\x3a\xa3\x55\x58\xb5\xc9\x40\x93\xde\x5f\x0c\x1b\xe0\xd4\x56\xb0\xe3\xb0\xc7\x01\x7f\x9a\xdb\x41\xf8\x05\x62\x6a\xf6\x65\xe3\xd5\xf9\x46\xb9\xb0\xa4\xa2\x68\xb1\x88\x06\x5a\xe1\xc2\x95\x45\xee\xaf\x07\x46\x63\x4d\xac\xb7\x9c\xce\x4e\x0b\xf7\xc1\x7e\xee\x7a\xf8\xeb\x43\x95\x28\x73\x06\xbe\xae\xa1\xff\x97\x5b\xa7\x35\xdf\x31\x35\xfc\x65\xf8\xf6\x3a\xd1\x9b\x2e\x36\x67\x43\x7f\x7b\x7b\xb7\x68\x4a\xd3\xbb\xe4\x71\x78\x94\x35\xf7\xc1\x8a\x2b\xd8\x4b\xb5\xa9\xfa\x57\x82\xed\x42\x9c\x90\x4a\xbc\x0b\xc3\xc9\x47\xf3\xaf\x9a\xa4\x1e\x3f\xbb\x0b\xd7\x3c\xfd\xf9\xbb\x67\x4a\xfb\x14\xc0\x63\x64\x8f\xf7\x1c\x2f\x4b\xb4\xbe\x5b\xda\xde\xc7\xf9\x50\xee\xe6\x3f\x90\x3b\xe3\xca\x17\xaa\x0a\x14\xfc\x0f\x08\x21\xf7\x67\x35\xf6\xb0\x78\x58\x0c\x1c\x55\xf5\x33\x05\x97\x0c\x94\xf2\xe9\xe3\xef\x75\x28\xbc\x3e\xaf\x6a\xb1\x58\x18\xc0\xa7\x13\xe0\x35\xd5\x31\xa0\x7c\xb1\x68\x15\xca\xb9\xce\xe6\xd8\xfa\xfb\xf3\xd3\x1f\x3f\x98\xa3\x92\x96\xa0\xc2\xaf\xe5\xba\x9f\x1e\x05\x25\xd8\x13\xc2\xfe\x5a\xc1\x25\x55\x49\x96\x63\x96\x5e\x21\x1d\xd9\x55\xc9\x13\xbd\x55\x30\x78\xa2\x3c\x07\xff\x01\xd9\xd3\xa0\xc4\x9f\x0d\x00\x00"
 
 func storyCssBytes() ([]byte, error) {
 	return bindataRead(
@@ -168,12 +273,12 @@ func storyCss() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "story.css", size: 1970, mode: os.FileMode(420), modTime: time.Unix(1533325117, 0)}
+	info := bindataFileInfo{name: "story.css", size: 3487, mode: os.FileMode(420), modTime: time.Unix(1792310110, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
 
-var _tocNcx = "\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8c\x90\xc1\x8e\xd3\x30\x14\x45\xf7\xf9\x0a\x8f\xf7\xf6\x2b\x20\x10\x44\x6e\x16\x34\xb3\xa8\x04\x9d\x4a\xb4\x0b\x96\x4e\xfc\x48\xac\x49\xec\x2a\x79\x9e\xa4\x58\xfe\x77\xe4\x0e\x42\x48\x2c\xca\xd2\xd7\xe7\xfa\x1e\x59\x3d\xd4\x4f\xbb\xd3\xf7\xe3\x23\x73\xed\xca\x8e\xe7\xcf\x5f\xf6\x3b\xc6\x05\xc0\x61\xff\xed\x09\xa0\x3e\xd5\xb7\x8b\xb7\x9b\xcd\x7b\xf1\x06\xe0\xf1\xc0\x0b\xc6\x7b\xa2\x4b\x09\xb0\x2c\x8b\x34\xda\xce\x57\xe9\xa7\x0e\x7e\xbe\xfb\xf4\xf1\x03\x64\x10\x5c\xbb\x8a\xd7\x86\x34\x64\x78\x55\xa8\x07\x21\x18\x5e\x42\xc3\x7e\xd8\x01\x59\x87\x0e\x27\x4d\x68\x58\x73\x65\xf9\xb1\xb9\x04\xe8\x2c\xf5\xa1\x91\xad\x1f\x61\xb2\xcf\xd6\x75\xb0\xf4\x9a\x70\xc0\xab\xc0\xc6\xfb\xe7\x99\x79\xc7\x62\x94\xb5\x26\x4c\x29\x37\x63\x94\xe7\x19\x27\xa7\xc7\x1c\x08\x51\x15\x2a\xbb\xae\xe3\xe0\xe6\xed\xff\x48\x02\x67\x2f\x38\xcd\xd6\xbb\x2d\x7f\xf5\xcd\xae\x3d\x6a\x53\x15\x6a\x44\xd2\xac\xf5\x8e\xd0\xd1\x96\x87\xc9\x95\x21\x58\x53\xe6\xd1\xf3\xbe\x4e\x89\xb3\x3c\xbc\xe5\x86\x9a\x32\x58\xc3\xe1\x9f\xce\xe6\x6f\xc4\xe0\x85\xfa\x7b\x10\x79\xd2\xc3\x51\x77\xb8\xf3\xc1\xd1\x3d\x7a\xd4\x6b\x66\x0f\x61\x6c\x70\xe2\x50\x29\xf8\xad\x6e\x7c\x7b\xb2\x34\x60\xa5\x08\x57\xaa\x62\x94\xb7\x63\x4a\x0a\x6e\x81\x82\x3f\x44\x11\xa3\x3c\xe8\x97\xaf\xfa\x92\x52\xa1\xf2\xa7\x54\xc5\xaf\x00\x00\x00\xff\xff\x4f\xe8\xb0\x8c\x14\x02\x00\x00"
+var _tocNcx = "\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x8d\x90\x41\x4f\x83\x40\x10\x85\xef\xfc\x8a\xed\xde\x61\xaa\x46\xa3\x0d\x70\x10\x7a\x68\xa2\x6d\x13\xdb\x83\xc7\x85\x1d\x61\x53\xd8\x6d\x60\x28\xd4\x86\xff\xee\xae\x35\x46\xa3\x89\x9e\x36\x33\xef\x7b\x33\x6f\x36\x9c\xa4\xab\x64\xf3\xbc\x9e\x33\x9d\x0f\x6c\xbd\xbd\x7f\x58\x24\x8c\xfb\x00\xcb\xc5\xd3\x0a\x20\xdd\xa4\xef\xc2\xe5\x74\x7a\xed\x5f\x00\xcc\x97\xdc\x63\xbc\x24\xda\xcf\x00\xfa\xbe\x0f\xa4\x50\xed\x31\x30\x4d\x01\xaf\x57\x77\xb7\x37\xe0\x40\xb0\x0e\xff\xec\x08\x24\x49\x1e\x7b\xe1\xc4\xf7\x19\xee\xbb\x8c\xbd\xa8\x0a\x59\x81\x1a\x1b\x41\x28\x59\x76\x64\x6e\x58\x6b\xa7\x15\x8a\xca\x2e\x0b\x72\x53\x43\xa3\x76\x4a\x17\xd0\x97\x96\xa9\xf0\xe8\x63\x66\xcc\xae\x65\x46\xb3\xd3\x29\x48\x6d\x73\x1c\x9d\xd3\x16\x49\x83\x82\x4c\x63\x6b\xdf\xb7\x6b\x5c\xd4\xa1\xae\x74\x1b\xfd\x27\x23\x70\x76\xc0\xa6\x55\x46\x47\xfc\x1c\xd7\x45\x2d\x51\x48\xfb\xd4\x48\x82\xe5\x46\x13\x6a\x8a\x78\xd7\xe8\x59\xd7\x29\x39\xb3\x3b\xb7\xdb\x45\x3a\x8e\x9c\x69\x51\x63\xc4\x25\x65\x33\x2b\x70\xf8\xe1\xb1\xe8\x66\x95\xa4\xb8\xa7\xf2\x3b\x2e\x5d\xeb\x17\xc3\xf4\x2b\x44\x86\x44\xb5\x16\x05\x26\xa6\xd3\xf4\x17\x5d\x8b\xc1\xb1\xcb\xae\xce\xb0\xb1\x70\x08\x1f\x67\x48\x93\x6f\x14\x55\x18\x87\x84\x03\xc5\x2e\x93\x2b\xc7\x31\x84\xf7\x46\x08\x9f\x84\x67\xc5\xa5\x38\x3c\x8a\xfd\x38\x7a\xa1\xfb\xa0\xd8\x7b\x03\x0b\x75\xa7\x43\x1f\x02\x00\x00"
 
 func tocNcxBytes() ([]byte, error) {
 	return bindataRead(
@@ -188,7 +293,7 @@ func tocNcx() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "toc.ncx", size: 532, mode: os.FileMode(420), modTime: time.Unix(1533332351, 0)}
+	info := bindataFileInfo{name: "toc.ncx", size: 543, mode: os.FileMode(420), modTime: time.Unix(1792307326, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
@@ -246,8 +351,13 @@ func AssetNames() []string {
 // _bindata is a table, holding each asset generator, mapped to its name.
 var _bindata = map[string]func() (*asset, error){
 	"content.opf": contentOpf,
+	"content3.opf": content3Opf,
+	"nav.xhtml": navXhtml,
 	"about.html": aboutHtml,
+	"attribution.html": attributionHtml,
+	"book.html": bookHtml,
 	"cover.html": coverHtml,
+	"cover-image.html": coverImageHtml,
 	"part.html": partHtml,
 	"story.css": storyCss,
 	"toc.ncx": tocNcx,
@@ -294,8 +404,13 @@ type bintree struct {
 }
 var _bintree = &bintree{nil, map[string]*bintree{
 	"about.html": &bintree{aboutHtml, map[string]*bintree{}},
+	"attribution.html": &bintree{attributionHtml, map[string]*bintree{}},
+	"book.html": &bintree{bookHtml, map[string]*bintree{}},
 	"content.opf": &bintree{contentOpf, map[string]*bintree{}},
+	"content3.opf": &bintree{content3Opf, map[string]*bintree{}},
+	"cover-image.html": &bintree{coverImageHtml, map[string]*bintree{}},
 	"cover.html": &bintree{coverHtml, map[string]*bintree{}},
+	"nav.xhtml": &bintree{navXhtml, map[string]*bintree{}},
 	"part.html": &bintree{partHtml, map[string]*bintree{}},
 	"story.css": &bintree{storyCss, map[string]*bintree{}},
 	"toc.ncx": &bintree{tocNcx, map[string]*bintree{}},
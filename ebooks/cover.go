@@ -0,0 +1,187 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package ebooks
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CoverDefinition is the optional `cover` section of a book definition.
+//
+// If Asset is set, that entry of Assets is used as the cover image. Otherwise an SVG cover with the
+// title, author, and series of the book is generated.
+//
+//	cover:
+//	  background: "#3b1f47"
+type CoverDefinition struct {
+	// the Target of an entry in Assets
+	Asset string
+	// CSS colors for the generated cover
+	Background string
+	Foreground string
+}
+
+const (
+	coverImageID       = "cover-image"
+	coverGeneratedFile = "Images/cover.svg"
+	coverPageID        = "Cover.html"
+	coverPageFile      = "Text/Cover.html"
+
+	defaultCoverBackground = "#1d2951"
+	defaultCoverForeground = "#ffffff"
+)
+
+var coverImagePageTmpl = template.Must(template.New("cover-image-page").Parse(string(MustAsset("cover-image.html"))))
+
+// CoverImageID is the manifest id of the cover image, or "" if the book has no cover.
+func (ed *EpubDefinition) CoverImageID() string {
+	if ed.Cover == nil {
+		return ""
+	}
+	if ed.Cover.Asset != "" {
+		return imageID(ed.Cover.Asset)
+	}
+	return coverImageID
+}
+
+// wrapWords breaks text into lines of at most width characters, where possible.
+func wrapWords(text string, width int) []string {
+	var lines []string
+	var cur string
+	for _, word := range strings.Fields(text) {
+		if cur == "" {
+			cur = word
+		} else if len(cur)+1+len(word) > width {
+			lines = append(lines, cur)
+			cur = word
+		} else {
+			cur = cur + " " + word
+		}
+	}
+	if cur != "" {
+		lines = append(lines, cur)
+	}
+	return lines
+}
+
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// RenderCoverSVG draws the generated cover: the series at the top, the title in the middle, and the
+// author at the bottom.
+func (ed *EpubDefinition) RenderCoverSVG() []byte {
+	const width, height = 600, 900
+	bg, fg := defaultCoverBackground, defaultCoverForeground
+	if ed.Cover != nil && ed.Cover.Background != "" {
+		bg = ed.Cover.Background
+	}
+	if ed.Cover != nil && ed.Cover.Foreground != "" {
+		fg = ed.Cover.Foreground
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		width, height, width, height)
+	fmt.Fprintf(&buf, `<rect x="0" y="0" width="%d" height="%d" fill="%s"/>`, width, height, xmlEscape(bg))
+	fmt.Fprintf(&buf, `<rect x="30" y="30" width="%d" height="%d" fill="none" stroke="%s" stroke-width="3"/>`,
+		width-60, height-60, xmlEscape(fg))
+	fmt.Fprintf(&buf, `<g fill="%s" font-family="serif" text-anchor="middle">`, xmlEscape(fg))
+
+	if ed.Series != "" {
+		for i, line := range wrapWords(ed.Series, 30) {
+			fmt.Fprintf(&buf, `<text x="%d" y="%d" font-size="30" font-style="italic">%s</text>`,
+				width/2, 110+i*38, xmlEscape(line))
+		}
+	}
+
+	titleLines := wrapWords(ed.Title, 16)
+	const titleSize, titleLeading = 60, 72
+	top := height/2 - (len(titleLines)-1)*titleLeading/2
+	for i, line := range titleLines {
+		fmt.Fprintf(&buf, `<text x="%d" y="%d" font-size="%d" font-weight="bold">%s</text>`,
+			width/2, top+i*titleLeading, titleSize, xmlEscape(line))
+	}
+
+	authorLines := wrapWords(ed.Author, 26)
+	for i, line := range authorLines {
+		fmt.Fprintf(&buf, `<text x="%d" y="%d" font-size="36">%s</text>`,
+			width/2, height-100-(len(authorLines)-1-i)*44, xmlEscape(line))
+	}
+
+	fmt.Fprint(&buf, `</g></svg>`)
+	return buf.Bytes()
+}
+
+// WriteCover writes the cover image (if generated) and the cover page, and marks the cover in the manifest.
+// It must be called after WriteAssets and before WriteText, so that the cover page starts the spine.
+func (ed *EpubDefinition) WriteCover(fs fileCreator) error {
+	ebookDir := "OEBPS"
+	if ed.Cover == nil {
+		return nil
+	}
+
+	var imageFile string
+	if ed.Cover.Asset != "" {
+		imageFile = "Images/" + ed.Cover.Asset
+		found := false
+		for i := range ed.files {
+			if ed.files[i].Filename == imageFile {
+				found = true
+				if ed.IsEpub3() {
+					ed.files[i].Properties = "cover-image"
+				}
+			}
+		}
+		if !found {
+			return errors.Errorf("cover asset %s is not in the assets list", ed.Cover.Asset)
+		}
+	} else {
+		imageFile = coverGeneratedFile
+		file, err := fs.Create(fmt.Sprintf("%s/%s", ebookDir, imageFile))
+		if err != nil {
+			return errors.Wrapf(err, "creating target file for %s", imageFile)
+		}
+		_, err = file.Write(ed.RenderCoverSVG())
+		if err != nil {
+			return errors.Wrapf(err, "writing target file for %s", imageFile)
+		}
+		entry := contentEntry{
+			Filename:    imageFile,
+			Id:          coverImageID,
+			ContentType: "image/svg+xml",
+		}
+		if ed.IsEpub3() {
+			entry.Properties = "cover-image"
+		}
+		ed.files = append(ed.files, entry)
+	}
+
+	doc, err := renderPage(ed.template("cover-image.html"), struct {
+		Title string
+		Image string
+	}{ed.Title, "../" + imageFile})
+	if err != nil {
+		return errors.Wrapf(err, "writing target file for %s", coverPageFile)
+	}
+	_, err = writeXHTMLFile(fs, fmt.Sprintf("%s/%s", ebookDir, coverPageFile), doc, ed.IsEpub3())
+	if err != nil {
+		return err
+	}
+	ed.files = append(ed.files, contentEntry{
+		Filename:    coverPageFile,
+		Id:          coverPageID,
+		ContentType: "application/xhtml+xml",
+		Landmark:    "cover",
+	})
+	return nil
+}
@@ -311,6 +311,9 @@ func FixForEbook(p *client.WhateleyPage) error {
 	// Fix double hrs
 	p.Doc().Find("hr + hr").Remove()
 
+	// Turn <center>, <font>, align= and inline styles into classes
+	writeLegacyReport(modernizeLegacyMarkup(p))
+
 	//p.Doc().Find("blockquote .lyrics .PCscreen").Unwrap()
 
 	return nil
@@ -0,0 +1,173 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package ebooks
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// A FontDefinition embeds a font file in the book, for text that the reader's fonts show poorly.
+type FontDefinition struct {
+	// File is a TTF, OTF, WOFF or WOFF2 file. Relative paths are relative to the definition file.
+	File string
+	// Family is the CSS font-family name. The default is the file name without extension.
+	Family string
+	// Weight and Style are the font-weight and font-style of the file, e.g. "bold" and "italic".
+	Weight string
+	Style  string
+	// Classes are CSS classes that are shown in the font, e.g. classes added by typo fixes.
+	Classes []string
+
+	target string
+	body   []byte
+}
+
+type fontType struct {
+	// media types for EPUB 2 readers and EPUB 3.2
+	mediaType2, mediaType3 string
+	format                 string
+	magic                  [][]byte
+}
+
+var fontTypes = map[string]fontType{
+	".ttf": {"application/x-font-ttf", "font/ttf", "truetype",
+		[][]byte{{0, 1, 0, 0}, []byte("true")}},
+	".otf": {"application/vnd.ms-opentype", "font/otf", "opentype",
+		[][]byte{[]byte("OTTO"), {0, 1, 0, 0}}},
+	".woff":  {"application/font-woff", "font/woff", "woff", [][]byte{[]byte("wOFF")}},
+	".woff2": {"font/woff2", "font/woff2", "woff2", [][]byte{[]byte("wOF2")}},
+}
+
+func (f *FontDefinition) fontType() fontType {
+	return fontTypes[strings.ToLower(path.Ext(f.target))]
+}
+
+// LoadFonts reads the font files of the book. Relative paths are relative to baseDir, the
+// directory of the definition file.
+func (ed *EpubDefinition) LoadFonts(baseDir string) error {
+	targets := make(map[string]string)
+	for i := range ed.Fonts {
+		f := &ed.Fonts[i]
+		file := f.File
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(baseDir, file)
+		}
+		ext := strings.ToLower(filepath.Ext(file))
+		typ, ok := fontTypes[ext]
+		if !ok {
+			return errors.Errorf("font %s: unknown font type %q, expected .ttf, .otf, .woff or .woff2", f.File, ext)
+		}
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return errors.Wrap(err, "could not read font")
+		}
+		magicOK := false
+		for _, m := range typ.magic {
+			if bytes.HasPrefix(b, m) {
+				magicOK = true
+			}
+		}
+		if !magicOK {
+			return errors.Errorf("font %s is not a %s file", f.File, typ.format)
+		}
+
+		stem := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		if f.Family == "" {
+			f.Family = stem
+		}
+		f.target = "Fonts/" + strings.Trim(unsafeFilenameChars.ReplaceAllString(stem, "-"), "-") + ext
+		if other, ok := targets[f.target]; ok {
+			return errors.Errorf("fonts %s and %s have the same file name", other, f.File)
+		}
+		targets[f.target] = f.File
+		f.body = b
+	}
+	return nil
+}
+
+// fontCSS returns the @font-face rules and class rules of the book's fonts. The files are linked
+// from Styles/, or embedded as data URIs if embed is set.
+func (ed *EpubDefinition) fontCSS(embed bool) string {
+	if len(ed.Fonts) == 0 {
+		return ""
+	}
+	var buf bytes.Buffer
+	buf.WriteString("\n/* fonts */\n")
+	classes := make(map[string][]string)
+	var classOrder []string
+	for i := range ed.Fonts {
+		f := &ed.Fonts[i]
+		if f.body == nil {
+			// not loaded, see LoadFonts
+			continue
+		}
+		src := "../" + f.target
+		if embed {
+			src = fmt.Sprintf("data:%s;base64,%s", f.fontType().mediaType3, base64.StdEncoding.EncodeToString(f.body))
+		}
+		fmt.Fprintf(&buf, "@font-face {\n    font-family: %q;\n    src: url(%s) format(%q);\n", f.Family, src, f.fontType().format)
+		if f.Weight != "" {
+			fmt.Fprintf(&buf, "    font-weight: %s;\n", f.Weight)
+		}
+		if f.Style != "" {
+			fmt.Fprintf(&buf, "    font-style: %s;\n", f.Style)
+		}
+		buf.WriteString("}\n")
+		for _, class := range f.Classes {
+			if _, ok := classes[class]; !ok {
+				classOrder = append(classOrder, class)
+			}
+			classes[class] = append(classes[class], f.Family)
+		}
+	}
+	for _, class := range classOrder {
+		// several files of one family, e.g. regular and bold, only need the family once
+		var families []string
+		seen := make(map[string]bool)
+		for _, family := range classes[class] {
+			if !seen[family] {
+				seen[family] = true
+				families = append(families, fmt.Sprintf("%q", family))
+			}
+		}
+		fmt.Fprintf(&buf, ".%s {\n    font-family: %s, serif;\n}\n", class, strings.Join(families, ", "))
+	}
+	return buf.String()
+}
+
+// WriteFonts adds the font files to the book.
+func (ed *EpubDefinition) WriteFonts(fs fileCreator) error {
+	for i := range ed.Fonts {
+		f := &ed.Fonts[i]
+		if f.body == nil {
+			return errors.Errorf("font %s was not loaded", f.File)
+		}
+		file, err := fs.Create("OEBPS/" + f.target)
+		if err != nil {
+			return errors.Wrapf(err, "creating target file for font %s", f.File)
+		}
+		_, err = file.Write(f.body)
+		if err != nil {
+			return errors.Wrapf(err, "writing font %s", f.File)
+		}
+		mediaType := f.fontType().mediaType2
+		if ed.IsEpub3() {
+			mediaType = f.fontType().mediaType3
+		}
+		ed.files = append(ed.files, contentEntry{
+			Filename:    f.target,
+			Id:          path.Base(f.target),
+			ContentType: mediaType,
+		})
+	}
+	return nil
+}
@@ -3,4 +3,4 @@
 
 package ebooks
 
-//go:generate go-bindata -nomemcopy -pkg ebooks content.opf about.html cover.html part.html story.css toc.ncx
+//go:generate go-bindata -nomemcopy -pkg ebooks content.opf content3.opf nav.xhtml about.html attribution.html book.html cover.html cover-image.html part.html story.css toc.ncx
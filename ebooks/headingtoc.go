@@ -0,0 +1,103 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package ebooks
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+
+	"github.com/riking/whateley-ebooks/client"
+)
+
+// ContentTOCSource is the toc-from-headings value that uses the site's own table of contents
+// (div.contenttoc) instead of a selector.
+const ContentTOCSource = "contenttoc"
+
+// headingTOC returns TOC entries for the headings inside a story. source is a CSS selector for the
+// headings, e.g. "h2, h3" or "p > strong:only-child", or ContentTOCSource. Headings without an id
+// get one, so this has to run before the page is rendered.
+func headingTOC(page *client.WhateleyPage, source, filename string, nest int) ([]contentEntry, error) {
+	body := page.StoryBodySelection()
+	var entries []contentEntry
+	add := func(title, anchor string) {
+		entries = append(entries, contentEntry{
+			Filename: filename + "#" + anchor,
+			TOC:      title,
+			TOCNest:  nest,
+		})
+	}
+
+	if source == ContentTOCSource {
+		for _, link := range page.ContentTOC() {
+			target := body.Find("[id]").FilterFunction(func(_ int, s *goquery.Selection) bool {
+				id, _ := s.Attr("id")
+				return id == link.Anchor
+			})
+			if target.Length() == 0 {
+				target = body.Find("a[name]").FilterFunction(func(_ int, s *goquery.Selection) bool {
+					name, _ := s.Attr("name")
+					return name == link.Anchor
+				})
+			}
+			if target.Length() == 0 {
+				fmt.Fprintf(os.Stderr, "[toc] #%s: contenttoc link %q points to missing anchor #%s\n",
+					page.StoryID, link.Title, link.Anchor)
+				continue
+			}
+			add(link.Title, link.Anchor)
+		}
+		if len(entries) == 0 {
+			fmt.Fprintf(os.Stderr, "[toc] #%s: no contenttoc links found\n", page.StoryID)
+		}
+		return entries, nil
+	}
+
+	sel, err := cascadia.Compile(source)
+	if err != nil {
+		return nil, errors.Wrapf(err, "bad toc-from-headings selector %q", source)
+	}
+	usedIDs := make(map[string]bool)
+	page.Doc().Find("[id]").Each(func(_ int, s *goquery.Selection) {
+		id, _ := s.Attr("id")
+		usedIDs[id] = true
+	})
+	next := 0
+	matched := make(map[*html.Node]bool)
+	body.FindMatcher(sel).Each(func(_ int, s *goquery.Selection) {
+		// "p.subhead, p > strong" matches a heading twice
+		for n := s.Nodes[0].Parent; n != nil; n = n.Parent {
+			if matched[n] {
+				return
+			}
+		}
+		matched[s.Nodes[0]] = true
+		title := strings.Join(strings.Fields(s.Text()), " ")
+		if title == "" {
+			return
+		}
+		id, ok := s.Attr("id")
+		if !ok || id == "" {
+			for {
+				next++
+				id = fmt.Sprintf("toc-%d", next)
+				if !usedIDs[id] {
+					break
+				}
+			}
+			usedIDs[id] = true
+			s.SetAttr("id", id)
+		}
+		add(title, id)
+	})
+	if len(entries) == 0 {
+		fmt.Fprintf(os.Stderr, "[toc] #%s: no headings matched %q\n", page.StoryID, source)
+	}
+	return entries, nil
+}
@@ -0,0 +1,80 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package ebooks
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readZipFile returns one file of a book.
+func readZipFile(t *testing.T, filename, name string) string {
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	t.Fatalf("%s has no %s", filename, name)
+	return ""
+}
+
+// The heading entries of a story without toc-nest must be children of the story entry.
+func TestHeadingTOCDefaultNest(t *testing.T) {
+	access, done := demoAccess(t)
+	defer done()
+
+	dir, err := ioutil.TempDir("", "headingtoc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, version := range []int{2, 3} {
+		ed := &EpubDefinition{Title: "Test", Author: "Demo Author", UUID: "urn:uuid:00000000-0000-4000-8000-000000000000", EpubVersion: version}
+		part := TOCEntry{TOC: "First Day", TOCFromHeadings: "p strong"}
+		part.Story.ID = "1"
+		ed.Parts = []TOCEntry{part}
+
+		filename := filepath.Join(dir, "test.epub")
+		err = CreateEpub(ed, access, filename)
+		if err != nil {
+			t.Fatalf("epub %d: %+v", version, err)
+		}
+
+		var toc, story, heading, close string
+		if version == 2 {
+			toc = readZipFile(t, filename, "OEBPS/toc.ncx")
+			story, heading, close = "<text>First Day</text>", "<text>Chapter 1</text>", "</navPoint>"
+		} else {
+			toc = readZipFile(t, filename, "OEBPS/nav.xhtml")
+			story, heading, close = ">First Day</a>", ">Chapter 1</a>", "</li>"
+		}
+		i, j := strings.Index(toc, story), strings.Index(toc, heading)
+		if i == -1 || j == -1 || j < i {
+			t.Fatalf("epub %d: missing TOC entries:\n%s", version, toc)
+		}
+		if strings.Contains(toc[i:j], close) {
+			t.Errorf("epub %d: heading is not nested under the story:\n%s", version, toc[i:j+len(heading)])
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package ebooks
+
+import (
+	"fmt"
+	"regexp"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/riking/whateley-ebooks/client"
+)
+
+// Kobo kepub output.
+//
+// A kepub is an epub where the text is split into <span class="koboSpan" id="kobo.P.S"> elements,
+// numbered by paragraph and sentence, and the body content is wrapped in #book-columns and
+// #book-inner. Kobo readers use the spans for page turns, reading statistics and highlights.
+
+// CreateKepub writes the book as a Kobo kepub file. The file name should end in .kepub.epub.
+func CreateKepub(ed *EpubDefinition, access *client.WANetwork, filename string) error {
+	ed.kepub = true
+	return CreateEpub(ed, access, filename)
+}
+
+// a sentence ends with punctuation, maybe closing quotes or brackets, and a space
+var kepubSentenceEnd = regexp.MustCompile(`[.!?\x{2026}]+['"\x{201d}\x{2019})\]]*\s+`)
+
+// kepubParagraphs start a new paragraph number.
+var kepubParagraphs = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Li: true, atom.Ol: true, atom.Ul: true, atom.Table: true, atom.Blockquote: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+}
+
+// kepubSkip are elements whose text is left alone.
+var kepubSkip = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Svg: true, atom.Math: true, atom.Pre: true,
+}
+
+type koboSpanner struct {
+	para, seg int
+}
+
+func (k *koboSpanner) span() *html.Node {
+	k.seg++
+	return &html.Node{
+		Type:     html.ElementNode,
+		Data:     "span",
+		DataAtom: atom.Span,
+		Attr: []html.Attribute{
+			{Key: "class", Val: "koboSpan"},
+			{Key: "id", Val: fmt.Sprintf("kobo.%d.%d", k.para, k.seg)},
+		},
+	}
+}
+
+// splitSentences cuts text after each sentence. The whitespace after a sentence stays with it.
+func splitSentences(text string) []string {
+	var parts []string
+	last := 0
+	for _, m := range kepubSentenceEnd.FindAllStringIndex(text, -1) {
+		if m[1] == len(text) {
+			break
+		}
+		parts = append(parts, text[last:m[1]])
+		last = m[1]
+	}
+	return append(parts, text[last:])
+}
+
+func (k *koboSpanner) walk(n *html.Node) {
+	var children []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		children = append(children, c)
+	}
+	for _, c := range children {
+		switch {
+		case c.Type == html.TextNode:
+			if isWhitespace(c.Data) {
+				continue
+			}
+			for _, sentence := range splitSentences(c.Data) {
+				s := k.span()
+				s.AppendChild(&html.Node{Type: html.TextNode, Data: sentence})
+				n.InsertBefore(s, c)
+			}
+			n.RemoveChild(c)
+		case c.Type != html.ElementNode || kepubSkip[c.DataAtom]:
+		case c.DataAtom == atom.Img:
+			s := k.span()
+			n.InsertBefore(s, c)
+			n.RemoveChild(c)
+			s.AppendChild(c)
+		default:
+			if kepubParagraphs[c.DataAtom] {
+				k.para++
+				k.seg = 0
+			}
+			k.walk(c)
+		}
+	}
+}
+
+func isWhitespace(s string) bool {
+	for _, r := range s {
+		if r != ' ' && r != '\t' && r != '\n' && r != '\r' && r != '\f' {
+			return false
+		}
+	}
+	return true
+}
+
+// kepubify adds the Kobo spans and wrapper divs to a rendered page.
+func kepubify(doc *html.Node) {
+	body := findBody(doc)
+	if body == nil {
+		return
+	}
+	inner := &html.Node{
+		Type:     html.ElementNode,
+		Data:     "div",
+		DataAtom: atom.Div,
+		Attr:     []html.Attribute{{Key: "id", Val: "book-inner"}},
+	}
+	columns := &html.Node{
+		Type:     html.ElementNode,
+		Data:     "div",
+		DataAtom: atom.Div,
+		Attr:     []html.Attribute{{Key: "id", Val: "book-columns"}},
+	}
+	for body.FirstChild != nil {
+		c := body.FirstChild
+		body.RemoveChild(c)
+		inner.AppendChild(c)
+	}
+	columns.AppendChild(inner)
+	body.AppendChild(columns)
+
+	k := koboSpanner{}
+	k.walk(inner)
+}
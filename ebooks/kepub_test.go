@@ -0,0 +1,67 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package ebooks
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestSplitSentences(t *testing.T) {
+	tests := []struct {
+		in  string
+		out []string
+	}{
+		{"One sentence.", []string{"One sentence."}},
+		{"One. Two! Three? ", []string{"One. ", "Two! ", "Three? "}},
+		{`"Quoted." She left.`, []string{`"Quoted." `, "She left."}},
+		{"Wait\u2026 what?! Yes.", []string{"Wait\u2026 ", "what?! ", "Yes."}},
+		{"(Aside.) Then\u201d more.\u201d end", []string{"(Aside.) ", "Then\u201d more.\u201d ", "end"}},
+		{"3.5 meters", []string{"3.5 meters"}},
+		{"", []string{""}},
+	}
+	for _, tt := range tests {
+		if out := splitSentences(tt.in); !reflect.DeepEqual(out, tt.out) {
+			t.Errorf("splitSentences(%q) = %q, expected %q", tt.in, out, tt.out)
+		}
+	}
+}
+
+func TestKepubify(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		out  string
+	}{
+		{"paragraphs", `<p>One. Two.</p><p>Three.</p>`,
+			`<p><span class="koboSpan" id="kobo.1.1">One. </span><span class="koboSpan" id="kobo.1.2">Two.</span></p>` +
+				`<p><span class="koboSpan" id="kobo.2.1">Three.</span></p>`},
+		{"inline markup", `<p>An <em>odd. Day</em> indeed.</p>`,
+			`<p><span class="koboSpan" id="kobo.1.1">An </span><em><span class="koboSpan" id="kobo.1.2">odd. </span>` +
+				`<span class="koboSpan" id="kobo.1.3">Day</span></em><span class="koboSpan" id="kobo.1.4"> indeed.</span></p>`},
+		{"whitespace is left alone", "<p>a</p>\n<p>b</p>",
+			`<p><span class="koboSpan" id="kobo.1.1">a</span></p>` + "\n" + `<p><span class="koboSpan" id="kobo.2.1">b</span></p>`},
+		{"image", `<p><img src="a.png"/></p>`, `<p><span class="koboSpan" id="kobo.1.1"><img src="a.png"/></span></p>`},
+		{"skipped", `<pre>a. b.</pre><p>c</p>`, `<pre>a. b.</pre><p><span class="koboSpan" id="kobo.1.1">c</span></p>`},
+		{"nested blocks", `<blockquote><p>a</p><p>b</p></blockquote>`,
+			`<blockquote><p><span class="koboSpan" id="kobo.2.1">a</span></p><p><span class="koboSpan" id="kobo.3.1">b</span></p></blockquote>`},
+	}
+	for _, tt := range tests {
+		doc, err := html.Parse(strings.NewReader("<html><head></head><body>" + tt.in + "</body></html>"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		kepubify(doc)
+		var buf bytes.Buffer
+		html.Render(&buf, findBody(doc))
+		want := `<body><div id="book-columns"><div id="book-inner">` + tt.out + `</div></div></body>`
+		if buf.String() != want {
+			t.Errorf("%s:\n  got: %s\n  exp: %s", tt.name, buf.String(), want)
+		}
+	}
+}
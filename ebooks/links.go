@@ -0,0 +1,135 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package ebooks
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/riking/whateley-ebooks/client"
+)
+
+// Values of EpubDefinition.OutsideLinks, for links to stories that are not in the book.
+const (
+	// OutsideLinksKeep points the link at the story on the site.
+	OutsideLinksKeep = "keep"
+	// OutsideLinksText removes the link and keeps its text.
+	OutsideLinksText = "text"
+)
+
+func (ed *EpubDefinition) outsideLinks() (string, error) {
+	switch ed.OutsideLinks {
+	case "", OutsideLinksKeep:
+		return OutsideLinksKeep, nil
+	case OutsideLinksText:
+		return OutsideLinksText, nil
+	}
+	return "", errors.Errorf("bad outside-links setting %q, expected %s or %s", ed.OutsideLinks, OutsideLinksKeep, OutsideLinksText)
+}
+
+// storyLinks rewrites links between the stories of a book.
+type storyLinks struct {
+	// story ID -> file name, relative to Text/
+	files   map[string]string
+	outside string
+	access  *client.WANetwork
+}
+
+// rewriteStoryLinks points links in the stories to other stories in the book at their files. Links to other stories,
+// and other links into the site, become absolute URLs or plain text, see OutsideLinks.
+func (ed *EpubDefinition) rewriteStoryLinks(access *client.WANetwork, parts []textPart) error {
+	outside, err := ed.outsideLinks()
+	if err != nil {
+		return err
+	}
+	l := storyLinks{files: make(map[string]string), outside: outside, access: access}
+	for _, v := range parts {
+		if v.doc != nil && v.StoryID != "" {
+			if _, ok := l.files[v.StoryID]; !ok {
+				l.files[v.StoryID] = path.Base(v.Filename)
+			}
+		}
+	}
+	for _, v := range parts {
+		// the about page links to the site on purpose
+		if v.doc != nil && v.StoryID != "" {
+			l.rewrite(v.doc)
+		}
+	}
+	return nil
+}
+
+// href returns the new link target. ok is false if the link should become text.
+func (l *storyLinks) href(href string) (target string, ok bool) {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") {
+		return href, true
+	}
+	ref, err := url.Parse(href)
+	if err != nil || (ref.Scheme != "" && ref.Scheme != "http" && ref.Scheme != "https") {
+		// mailto: and friends
+		return href, true
+	}
+	if ref.Scheme == "" && ref.Host == "" && !strings.HasPrefix(ref.Path, "/") {
+		// relative link inside the book
+		return href, true
+	}
+	u := l.access.ResolveReference(ref)
+	if !l.access.IsSiteHost(u.Host) {
+		return href, true
+	}
+
+	story, err := l.access.ParseURL(u.String())
+	if err != nil {
+		// not a story, e.g. a tag or category page
+		return u.String(), true
+	}
+	if file, ok := l.files[story.StoryID]; ok {
+		if u.Fragment != "" {
+			return file + "#" + u.Fragment, true
+		}
+		return file, true
+	}
+	if l.outside == OutsideLinksText {
+		return "", false
+	}
+	return u.String(), true
+}
+
+func (l *storyLinks) rewrite(n *html.Node) {
+	if n.Type == html.ElementNode && hasClass(n, "attribution") {
+		// the link to the story on the site, see attribution.html
+		return
+	}
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		l.rewrite(c)
+		c = next
+	}
+	if n.Type != html.ElementNode || n.DataAtom != atom.A {
+		return
+	}
+	for i, a := range n.Attr {
+		if a.Key != "href" {
+			continue
+		}
+		if href, ok := l.href(a.Val); ok {
+			n.Attr[i].Val = href
+			return
+		}
+		// replace the link with its content
+		for n.FirstChild != nil {
+			c := n.FirstChild
+			n.RemoveChild(c)
+			n.Parent.InsertBefore(c, n)
+		}
+		n.Parent.RemoveChild(n)
+		return
+	}
+}
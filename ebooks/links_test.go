@@ -0,0 +1,99 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package ebooks
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+
+	"github.com/riking/whateley-ebooks/client"
+)
+
+func TestStoryLinksHref(t *testing.T) {
+	access := client.New(client.Options{MaxRetries: -1})
+	files := map[string]string{"34": "34-whisper-one.html", "35": "35-part-two.html"}
+	keep := &storyLinks{files: files, outside: OutsideLinksKeep, access: access}
+	text := &storyLinks{files: files, outside: OutsideLinksText, access: access}
+
+	tests := []struct {
+		name string
+		in   string
+		keep string
+		text string
+	}{
+		{"story in the book", "http://whateleyacademy.net/index.php/original-timeline/35-part-two", "35-part-two.html", "35-part-two.html"},
+		{"other host name and fragment", "https://www.whateleyacademy.net/index.php/original-timeline/34-whisper-one#epilog", "34-whisper-one.html#epilog", "34-whisper-one.html#epilog"},
+		{"site-relative story link", "/index.php/original-timeline/35-wrong-slug", "35-part-two.html", "35-part-two.html"},
+		{"story outside the book", "http://whateleyacademy.net/index.php/original-timeline/99-other", "http://whateleyacademy.net/index.php/original-timeline/99-other", ""},
+		{"site page that is not a story", "/index.php/component/tags/tag/3-demo", "http://whateleyacademy.net/index.php/component/tags/tag/3-demo", "http://whateleyacademy.net/index.php/component/tags/tag/3-demo"},
+		{"other site", "http://example.com/index.php/original-timeline/35-part-two", "http://example.com/index.php/original-timeline/35-part-two", "http://example.com/index.php/original-timeline/35-part-two"},
+		{"fragment", "#note1", "#note1", "#note1"},
+		{"relative link in the book", "35-part-two.html#x", "35-part-two.html#x", "35-part-two.html#x"},
+		{"mailto", "mailto:someone@example.com", "mailto:someone@example.com", "mailto:someone@example.com"},
+	}
+	for _, tt := range tests {
+		if out, ok := keep.href(tt.in); !ok || out != tt.keep {
+			t.Errorf("%s (keep): got %q, %v; expected %q", tt.name, out, ok, tt.keep)
+		}
+		out, ok := text.href(tt.in)
+		if tt.text == "" && ok {
+			t.Errorf("%s (text): got %q, expected the link to become text", tt.name, out)
+		} else if tt.text != "" && (!ok || out != tt.text) {
+			t.Errorf("%s (text): got %q, %v; expected %q", tt.name, out, ok, tt.text)
+		}
+	}
+}
+
+func TestRewriteStoryLinks(t *testing.T) {
+	access := client.New(client.Options{MaxRetries: -1})
+	part := func(filename, storyID, body string) textPart {
+		doc, err := html.Parse(strings.NewReader("<body>" + body + "</body>"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return textPart{contentEntry{Filename: filename, StoryID: storyID}, doc}
+	}
+	body := `<p>See <a href="/index.php/original-timeline/34-whisper-one">part <em>one</em></a> and ` +
+		`<a href="/index.php/original-timeline/99-other">another story</a>.</p>` +
+		`<p class="attribution"><a href="http://whateleyacademy.net/index.php/original-timeline/35-part-two">on the site</a></p>`
+
+	for _, tt := range []struct {
+		outside string
+		out     string
+	}{
+		{"", `<p>See <a href="34-whisper-one.html">part <em>one</em></a> and ` +
+			`<a href="http://whateleyacademy.net/index.php/original-timeline/99-other">another story</a>.</p>`},
+		{OutsideLinksText, `<p>See <a href="34-whisper-one.html">part <em>one</em></a> and another story.</p>`},
+	} {
+		parts := []textPart{
+			part("Text/34-whisper-one.html", "34", `<p>One</p>`),
+			part("Text/35-part-two.html", "35", body),
+			part("Text/About.html", "", `<a href="/index.php/original-timeline/34-whisper-one">site</a>`),
+		}
+		ed := &EpubDefinition{OutsideLinks: tt.outside}
+		err := ed.rewriteStoryLinks(access, parts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		html.Render(&buf, findBody(parts[1].doc))
+		want := "<body>" + tt.out + `<p class="attribution"><a href="http://whateleyacademy.net/index.php/original-timeline/35-part-two">on the site</a></p></body>`
+		if buf.String() != want {
+			t.Errorf("outside-links %q:\n  got: %s\n  exp: %s", tt.outside, buf.String(), want)
+		}
+		buf.Reset()
+		html.Render(&buf, findBody(parts[2].doc))
+		if !strings.Contains(buf.String(), `href="/index.php/original-timeline/34-whisper-one"`) {
+			t.Errorf("the about page was rewritten: %s", buf.String())
+		}
+	}
+
+	ed := &EpubDefinition{OutsideLinks: "drop"}
+	if err := ed.rewriteStoryLinks(access, nil); err == nil {
+		t.Errorf("bad outside-links setting was accepted")
+	}
+}
@@ -10,10 +10,10 @@ import (
 	"fmt"
 	"html/template"
 	"io"
-	"net/http"
 	"os"
 	"os/user"
 	"runtime"
+	"sort"
 	"sync"
 	"time"
 
@@ -39,6 +39,12 @@ type TOCEntry struct {
 		RemoveTitles string `yaml:"remove-titles"`
 		page         *client.WhateleyPage
 	}
+
+	// Attribution overrides EpubDefinition.Attribution for this story.
+	Attribution *bool
+	// TOCFromHeadings adds child TOC entries for the headings in the story: a CSS selector like
+	// "h2, h3", or "contenttoc" to use the site's table of contents.
+	TOCFromHeadings string `yaml:"toc-from-headings"`
 }
 
 func (t *TOCEntry) IsCoverPage() bool {
@@ -103,6 +109,11 @@ func (t *TOCEntry) preparePageB(access *client.WANetwork, ed *EpubDefinition, pa
 	}
 	// perform RemoveTitles
 	page.Doc().Find(t.Story.RemoveTitles).Remove()
+	if ed.textOnly {
+		absoluteImageURLs(access, page)
+		t.Story.page = page
+		return nil
+	}
 	// perform asset replacement
 	for _, asset := range ed.Assets {
 		s := page.StoryBodySelection().FindMatcher(cascadia.Selector(findMatchingSrc(asset.Find)))
@@ -110,6 +121,8 @@ func (t *TOCEntry) preparePageB(access *client.WANetwork, ed *EpubDefinition, pa
 			s.SetAttr("src", fmt.Sprintf("../Images/%s", asset.Target))
 		}
 	}
+	// bundle all other images
+	ed.bundleImages(access, page)
 
 	t.Story.page = page
 	return nil
@@ -121,7 +134,7 @@ func (t *TOCEntry) preparePageB(access *client.WANetwork, ed *EpubDefinition, pa
 type EpubDefinition struct {
 	Parts  []TOCEntry
 	Assets []struct {
-		// the URL to download the asset from (should start with http://whateleyacademy.net)
+		// the URL to download the asset from (default: images/Target on the site, see assetDownload)
 		Download string
 		// the location in the epub of the asset
 		Target string
@@ -136,17 +149,67 @@ type EpubDefinition struct {
 	Publisher  string
 	Series     string
 	UUID       string
+	// SeriesIndex is the position of the book in the series, for calibre:series_index.
+	SeriesIndex float64 `yaml:"series-index"`
+	// Language is the language code of the book. The default is "en".
+	Language string
+	// Description is the blurb shown by library software.
+	Description string
+	// EpubVersion selects the package format: 2 (OPF 2.0 + NCX) or 3 (OPF 3.0 + nav.xhtml).
+	// The zero value produces an EPUB 2 file.
+	EpubVersion int `yaml:"epub-version"`
+	// Cover adds a cover image, see CoverDefinition.
+	Cover *CoverDefinition
+	// CreatedBy is written into the book as the person who made the file.
+	// If empty, the current user and host name are used.
+	CreatedBy string `yaml:"created-by"`
+	// Stories larger than SplitSize bytes or longer than SplitWords words are split into several
+	// files at scene breaks and headings. Both are off unless set, except that the profile's size
+	// limit applies when SplitSize is not set; -1 turns splitting by size off. 266240 (260 KB)
+	// keeps files under the 300 KB limit of older Adobe based readers.
+	SplitSize  int `yaml:"split-size"`
+	SplitWords int `yaml:"split-words"`
+	// OutsideLinks is what happens to links to stories that are not in the book: "keep" links to
+	// the site (the default), "text" removes the link.
+	OutsideLinks string `yaml:"outside-links"`
+	// Attribution puts a header with the title, author, publish date and URL before every story.
+	// If Author is empty, it is made from the story authors.
+	Attribution bool
+	// Templates replaces embedded templates, by name: about.html, attribution.html, cover.html,
+	// cover-image.html, part.html or story.css. Paths are relative to the definition file.
+	Templates map[string]string
+	// Stylesheets are added after story.css.
+	Stylesheets []string
+	// Fonts are embedded in the book, see LoadFonts.
+	Fonts []FontDefinition
+
+	reproducible     bool
+	reproducibleTime time.Time
+	// kepub adds Kobo spans to every page, see CreateKepub
+	kepub bool
+	// textOnly leaves images at their address on the site instead of bundling them, see CreateText
+	textOnly bool
+	profile  *Profile
+	// see LoadTemplates
+	templates map[string]*template.Template
+	css       []byte
+	extraCSS  []byte
 
 	files     contentEntries
 	lock      sync.Mutex
 	wordCount int
 
+	images    imageState
+	imageLock sync.Mutex
+	// the site the stories come from, for the about page
+	siteURL string
+
 	assetPrepareDone bool
 }
 
 func (ed *EpubDefinition) Clone() *EpubDefinition {
-	return &EpubDefinition{
-		Parts:      ed.Parts,
+	clone := &EpubDefinition{
+		Parts:      append([]TOCEntry(nil), ed.Parts...),
 		Assets:     ed.Assets,
 		Author:     ed.Author,
 		AuthorSort: ed.AuthorSort,
@@ -155,7 +218,37 @@ func (ed *EpubDefinition) Clone() *EpubDefinition {
 		Publisher:  ed.Publisher,
 		Series:     ed.Series,
 		UUID:       ed.UUID,
+
+		SeriesIndex: ed.SeriesIndex,
+		Language:    ed.Language,
+		Description: ed.Description,
+
+		EpubVersion: ed.EpubVersion,
+		Cover:       ed.Cover,
+		CreatedBy:   ed.CreatedBy,
+		SplitSize:   ed.SplitSize,
+		SplitWords:  ed.SplitWords,
+
+		OutsideLinks: ed.OutsideLinks,
+		Attribution:  ed.Attribution,
+		Templates:    ed.Templates,
+		Stylesheets:  ed.Stylesheets,
+		Fonts:        ed.Fonts,
+
+		reproducible:     ed.reproducible,
+		reproducibleTime: ed.reproducibleTime,
+		profile:          ed.profile,
+		templates:        ed.templates,
+		css:              ed.css,
+		extraCSS:         ed.extraCSS,
+
+		assetPrepareDone: ed.assetPrepareDone,
 	}
+	// Stories that were already prepared are shared, so the images found in them are needed too
+	ed.imageLock.Lock()
+	clone.images = ed.images.clone()
+	ed.imageLock.Unlock()
+	return clone
 }
 
 func (ed *EpubDefinition) AuthorFileAs() string {
@@ -165,15 +258,64 @@ func (ed *EpubDefinition) AuthorFileAs() string {
 	return ed.AuthorSort
 }
 
+// SetReproducible makes every build of the definition produce the same bytes: the build time is
+// fixed to t, and nothing about the current machine or user is written into the book.
+func (ed *EpubDefinition) SetReproducible(t time.Time) {
+	ed.reproducible = true
+	ed.reproducibleTime = t.UTC()
+}
+
+// buildTime is the time used for dates in the book and the zip file timestamps.
+func (ed *EpubDefinition) buildTime() time.Time {
+	if ed.reproducible {
+		return ed.reproducibleTime
+	}
+	return time.Now().UTC()
+}
+
+// Creator identifies who made the book file. It is taken from the created-by setting, or the current
+// user and host name if that is not set.
+func (ed *EpubDefinition) Creator() (string, error) {
+	if ed.CreatedBy != "" {
+		return ed.CreatedBy, nil
+	}
+	if ed.reproducible {
+		return "anonymous", nil
+	}
+	username, err := ed.Username()
+	if err != nil {
+		return "", err
+	}
+	hostname, err := ed.Hostname()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s@%s", username, hostname), nil
+}
+
 func (ed *EpubDefinition) Date() string {
-	return time.Now().UTC().Format("2006-01-02T15:04:05-07:00")
+	return ed.buildTime().Format("2006-01-02T15:04:05-07:00")
+}
+
+// IsEpub3 reports whether the book is written as an EPUB 3 package.
+func (ed *EpubDefinition) IsEpub3() bool {
+	return ed.EpubVersion >= 3
+}
+
+// Modified is the value of the dcterms:modified property required by EPUB 3.
+func (ed *EpubDefinition) Modified() string {
+	return ed.buildTime().Format("2006-01-02T15:04:05Z")
 }
 
 func (ed *EpubDefinition) Hostname() (string, error) {
 	return os.Hostname()
 }
 
+// MachineID is empty for reproducible builds.
 func (ed *EpubDefinition) MachineID() (string, error) {
+	if ed.reproducible {
+		return "", nil
+	}
 	return machineid.ID()
 }
 
@@ -198,9 +340,6 @@ func (ed *EpubDefinition) PrepareAssets() {
 	}
 	for i := range ed.Assets {
 		v := &ed.Assets[i]
-		if v.Download == "" {
-			v.Download = fmt.Sprintf("http://whateleyacademy.net/images/%s", v.Target)
-		}
 		if v.Find == "" {
 			v.Find = fmt.Sprintf("/images/%s", v.Target)
 		}
@@ -310,17 +449,29 @@ type contentEntry struct {
 	ContentType string
 	TOC         string
 	TOCNest     int
+	// EPUB 3 manifest properties, e.g. "nav"
+	Properties string
+	// Landmark is the epub:type of the entry in the landmarks nav / guide, e.g. "bodymatter"
+	Landmark string
+	// StoryID is the story the file was generated from, for error reporting
+	StoryID string
 }
 
 func (c *contentEntry) RenderManifest(w io.Writer) {
 	if c.Id != "" {
+		if c.Properties != "" {
+			fmt.Fprintf(w, `<item href="%s" id="%s" media-type="%s" properties="%s"/>`,
+				html.EscapeString(c.Filename), html.EscapeString(c.Id), html.EscapeString(c.ContentType), html.EscapeString(c.Properties))
+			return
+		}
 		fmt.Fprintf(w, `<item href="%s" id="%s" media-type="%s"/>`,
 			html.EscapeString(c.Filename), html.EscapeString(c.Id), html.EscapeString(c.ContentType))
 	}
 }
 
 func (c *contentEntry) RenderSpine(w io.Writer) {
-	if c.ContentType == "application/xhtml+xml" {
+	// The nav document is reachable through the reader's TOC, it is not part of the reading order
+	if c.ContentType == "application/xhtml+xml" && c.Properties != "nav" {
 		fmt.Fprintf(w, `<itemref idref="%s"/>`, html.EscapeString(c.Id))
 	}
 }
@@ -383,6 +534,10 @@ func (c contentEntries) RenderInTocNCX(w io.Writer) {
 			// default value of 1, since 0 has special meaning
 			v.TOCNest = 1
 		}
+		if v.TOCNest > nest+1 {
+			// skipping a level would leave navPoints unbalanced
+			v.TOCNest = nest + 1
+		}
 		if v.TOC != "" {
 			sequence++
 			nest = v.RenderNavPoint(w, sequence, nest)
@@ -397,7 +552,116 @@ func (c contentEntries) RenderInTocNCX(w io.Writer) {
 	fmt.Fprint(w, "</navMap>")
 }
 
+// RenderInNavXHTML renders the EPUB 3 table of contents as nested <ol> lists.
+// The nesting follows the same toc-nest rules as RenderInTocNCX.
+// A level can only be one deeper than the entry before it, deeper jumps are clamped.
+func (c contentEntries) RenderInNavXHTML(w io.Writer) {
+	fmt.Fprint(w, "<ol>")
+	depth := 0
+	for _, v := range c {
+		if v.TOC == "" {
+			continue
+		}
+		level := v.TOCNest
+		if level == 0 {
+			level = 1
+		}
+		if level > depth+1 {
+			level = depth + 1
+		}
+
+		if level > depth {
+			if depth > 0 {
+				fmt.Fprint(w, "<ol>")
+			}
+		} else {
+			fmt.Fprint(w, "</li>")
+			for depth > level {
+				fmt.Fprint(w, "</ol></li>")
+				depth--
+			}
+		}
+		fmt.Fprintf(w, `<li><a href="%s">%s</a>`,
+			template.HTMLEscapeString(v.Filename), template.HTMLEscapeString(v.TOC))
+		depth = level
+	}
+	for depth > 0 {
+		fmt.Fprint(w, "</li>")
+		if depth > 1 {
+			fmt.Fprint(w, "</ol>")
+		}
+		depth--
+	}
+	fmt.Fprint(w, "</ol>")
+}
+
+var landmarkTitles = map[string]string{
+	"cover":      "Cover",
+	"toc":        "Table of Contents",
+	"bodymatter": "Start of Content",
+}
+
+// EPUB 2 <guide> reference types for each landmark
+var landmarkGuideTypes = map[string]string{
+	"cover":      "cover",
+	"toc":        "toc",
+	"bodymatter": "text",
+}
+
+// RenderLandmarks renders the list inside the EPUB 3 landmarks nav.
+func (c contentEntries) RenderLandmarks(w io.Writer) {
+	fmt.Fprint(w, "<ol>")
+	for _, v := range c {
+		if v.Landmark == "" {
+			continue
+		}
+		fmt.Fprintf(w, `<li><a epub:type="%s" href="%s">%s</a></li>`,
+			template.HTMLEscapeString(v.Landmark), template.HTMLEscapeString(v.Filename),
+			template.HTMLEscapeString(landmarkTitles[v.Landmark]))
+	}
+	fmt.Fprint(w, "</ol>")
+}
+
+// RenderGuide renders the OPF <guide> element. EPUB 3 readers use the landmarks nav instead, but
+// the guide is kept for older readers.
+func (c contentEntries) RenderGuide(w io.Writer) {
+	fmt.Fprint(w, "<guide>")
+	for _, v := range c {
+		guideType, ok := landmarkGuideTypes[v.Landmark]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(w, `<reference type="%s" title="%s" href="%s"/>`,
+			guideType, template.HTMLEscapeString(landmarkTitles[v.Landmark]), template.HTMLEscapeString(v.Filename))
+	}
+	fmt.Fprint(w, "</guide>")
+}
+
+// TOCDepth is the number of nesting levels in the rendered table of contents, for dtb:depth.
+func (c contentEntries) TOCDepth() int {
+	maxDepth := 0
+	prevDepth := 0
+	for _, v := range c {
+		if v.TOC == "" {
+			continue
+		}
+		depth := v.TOCNest
+		if depth == 0 {
+			depth = 1
+		}
+		if depth > prevDepth+1 {
+			depth = prevDepth + 1
+		}
+		prevDepth = depth
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+	}
+	return maxDepth
+}
+
 var contentOPFTmpl = template.Must(template.New("content.opf").Parse(string(MustAsset("content.opf"))))
+var contentOPF3Tmpl = template.Must(template.New("content3.opf").Parse(string(MustAsset("content3.opf"))))
 
 func (ed *EpubDefinition) ManifestAndSpine() template.HTML {
 	var buf bytes.Buffer
@@ -405,10 +669,37 @@ func (ed *EpubDefinition) ManifestAndSpine() template.HTML {
 	return template.HTML(buf.String())
 }
 
+func (ed *EpubDefinition) Guide() template.HTML {
+	var buf bytes.Buffer
+	ed.files.RenderGuide(&buf)
+	return template.HTML(buf.String())
+}
+
 func (ed *EpubDefinition) RenderContentOPF(w io.Writer) error {
+	if ed.IsEpub3() {
+		return contentOPF3Tmpl.Execute(w, ed)
+	}
 	return contentOPFTmpl.Execute(w, ed)
 }
 
+var navXHTMLTmpl = template.Must(template.New("nav.xhtml").Parse(string(MustAsset("nav.xhtml"))))
+
+func (ed *EpubDefinition) NavList() template.HTML {
+	var buf bytes.Buffer
+	ed.files.RenderInNavXHTML(&buf)
+	return template.HTML(buf.String())
+}
+
+func (ed *EpubDefinition) LandmarksList() template.HTML {
+	var buf bytes.Buffer
+	ed.files.RenderLandmarks(&buf)
+	return template.HTML(buf.String())
+}
+
+func (ed *EpubDefinition) RenderNavDocument(w io.Writer) error {
+	return navXHTMLTmpl.Execute(w, ed)
+}
+
 var tocNCXTmpl = template.Must(template.New("toc.ncx").Parse(string(MustAsset("toc.ncx"))))
 
 func (ed *EpubDefinition) NavMap() template.HTML {
@@ -417,6 +708,22 @@ func (ed *EpubDefinition) NavMap() template.HTML {
 	return template.HTML(buf.String())
 }
 
+func (ed *EpubDefinition) TOCDepth() int {
+	return ed.files.TOCDepth()
+}
+
+// Sources maps each file written to the zip to the ID of the story it was generated from.
+// It is only complete after CreateEpub has run.
+func (ed *EpubDefinition) Sources() map[string]string {
+	m := make(map[string]string)
+	for _, v := range ed.files {
+		if v.StoryID != "" && v.Id != "" {
+			m["OEBPS/"+v.Filename] = v.StoryID
+		}
+	}
+	return m
+}
+
 func (ed *EpubDefinition) RenderTOC(w io.Writer) error {
 	return tocNCXTmpl.Execute(w, ed)
 }
@@ -442,6 +749,33 @@ func (ed *EpubDefinition) WriteTableOfContents(fs fileCreator) error {
 	return nil
 }
 
+// WriteNavDocument writes the EPUB 3 navigation document.
+// It must be called after WriteText, as the TOC is built from the written text files.
+func (ed *EpubDefinition) WriteNavDocument(fs fileCreator) error {
+	ebookDir := "OEBPS"
+	filename := "nav.xhtml"
+	// The landmarks list includes the nav itself, so it has to be registered first
+	ed.files = append(ed.files, contentEntry{
+		Filename:    filename,
+		Id:          "nav",
+		ContentType: "application/xhtml+xml",
+		Properties:  "nav",
+		Landmark:    "toc",
+	})
+
+	file, err := fs.Create(fmt.Sprintf("%s/%s", ebookDir, filename))
+	if err != nil {
+		return errors.Wrapf(err, "creating target file for asset %s", filename)
+	}
+	file.Write([]byte(`<?xml version="1.0" encoding="utf-8"?>
+`))
+	err = ed.RenderNavDocument(file)
+	if err != nil {
+		return errors.Wrapf(err, "processing asset %s", filename)
+	}
+	return nil
+}
+
 func (ed *EpubDefinition) WriteContentOPF(fs fileCreator) error {
 	ebookDir := "OEBPS"
 	filename := "content.opf"
@@ -498,9 +832,9 @@ func (ed *EpubDefinition) WriteStyles(fs fileCreator) error {
 	if err != nil {
 		return errors.Wrapf(err, "creating target file for asset %s", id)
 	}
-	b, err := Asset("story.css")
+	b, err := ed.styleSheet(false)
 	if err != nil {
-		return errors.Wrapf(err, "could not find embedded asset %s", id)
+		return err
 	}
 	w := bufio.NewWriter(file)
 	_, err = w.Write(b)
@@ -521,36 +855,25 @@ func (ed *EpubDefinition) WriteStyles(fs fileCreator) error {
 
 func (ed *EpubDefinition) WriteAssets(access *client.WANetwork, fs fileCreator) error {
 	ebookDir := "OEBPS"
-	for _, v := range ed.Assets {
+	images, err := ed.collectImages(access)
+	if err != nil {
+		return err
+	}
+	for _, v := range images {
 		filename := fmt.Sprintf("Images/%s", v.Target)
 		file, err := fs.Create(fmt.Sprintf("%s/%s", ebookDir, filename))
 		if err != nil {
 			return errors.Wrapf(err, "creating target file for asset %s", v.Target)
 		}
-
-		if v.Download == "" {
-			v.Download = fmt.Sprintf("http://whateleyacademy.net/images/%s", v.Target)
-		}
-
-		req, err := http.NewRequest("GET", v.Download, nil)
-		if err != nil {
-			panic(err)
-		}
-
-		body, contentType, err := access.GetAsset(req)
-		if err != nil {
-			return errors.Wrapf(err, "downloading asset %s", v.Download)
-		}
-
-		_, err = file.Write(body)
+		_, err = file.Write(v.body)
 		if err != nil {
 			return errors.Wrapf(err, "writing asset file %s", v.Target)
 		}
 
 		ed.files = append(ed.files, contentEntry{
 			Filename:    filename,
-			Id:          v.Target,
-			ContentType: contentType,
+			Id:          imageID(v.Target),
+			ContentType: v.contentType,
 		})
 	}
 	return nil
@@ -574,105 +897,141 @@ func findMatchingSrc(src string) func(*html.Node) bool {
 	}
 }
 
-func (ed *EpubDefinition) WriteText(access *client.WANetwork, fs fileCreator) error {
-	ebookDir := "OEBPS"
+// A textPart is a rendered page of the book, before it is written out.
+// Anchor entries have no page of their own and a nil doc.
+type textPart struct {
+	contentEntry
+	doc *html.Node
+}
 
+// renderText renders the cover pages, the about page, and the stories.
+func (ed *EpubDefinition) renderText(access *client.WANetwork) ([]textPart, error) {
+	var parts []textPart
+	ed.wordCount = 0
 	coverCount := 0
+	bodyMarked := false
 	for _, v := range ed.Parts {
-		var filename string
 		if v.IsCoverPage() && !v.fixCvr {
 			coverCount++
 			id := fmt.Sprintf("Cover%02d.html", coverCount)
-			filename = "Text/" + id
-			file, err := fs.Create(fmt.Sprintf("%s/%s", ebookDir, filename))
-			if err != nil {
-				return errors.Wrapf(err, "creating target file for %s", filename)
-			}
-			w := bufio.NewWriter(file)
-			w.WriteString(`<?xml version="1.0" encoding="utf-8" standalone="no"?>`)
-			err = coverPageTmpl.Execute(w, template.HTML(v.CoverPage))
-			if err != nil {
-				return errors.Wrapf(err, "writing target file for %s", filename)
-			}
-			err = w.Flush()
+			filename := "Text/" + id
+			doc, err := renderPage(ed.template("cover.html"), template.HTML(v.CoverPage))
 			if err != nil {
-				return errors.Wrapf(err, "writing target file for %s", filename)
+				return nil, errors.Wrapf(err, "writing target file for %s", filename)
 			}
 
-			ed.files = append(ed.files, contentEntry{
+			parts = append(parts, textPart{contentEntry{
 				Filename:    filename,
 				Id:          id,
 				ContentType: "application/xhtml+xml",
 				TOC:         v.TOC,
 				TOCNest:     v.TOCNest,
-			})
+			}, doc})
 		} else if v.fixCvr {
 			id := "About.html"
 			filename := "Text/" + id
-			file, err := fs.Create(fmt.Sprintf("%s/%s", ebookDir, filename))
+			doc, err := renderPage(ed.template("about.html"), ed)
 			if err != nil {
-				return errors.Wrapf(err, "creating target file for %s", filename)
-			}
-			w := bufio.NewWriter(file)
-			w.WriteString(`<?xml version="1.0" encoding="utf-8" standalone="no"?>`)
-			err = aboutPageTmpl.Execute(w, ed)
-			if err != nil {
-				return errors.Wrapf(err, "writing target file for %s", filename)
-			}
-			err = w.Flush()
-			if err != nil {
-				return errors.Wrapf(err, "writing target file for %s", filename)
+				return nil, errors.Wrapf(err, "writing target file for %s", filename)
 			}
 
-			ed.files = append(ed.files, contentEntry{
+			parts = append(parts, textPart{contentEntry{
 				Filename:    filename,
 				Id:          id,
 				ContentType: "application/xhtml+xml",
 				TOC:         "",
 				TOCNest:     0,
-			})
+			}, doc})
 		} else if v.IsContentPage() {
 			page, err := v.preparePage(access, ed)
 			if err != nil {
-				return errors.Wrapf(err, "preparing content for story #%s", v.Story.ID)
+				return nil, errors.Wrapf(err, "preparing content for story #%s", v.Story.ID)
 			}
 			id := fmt.Sprintf("%s-%s.html", page.StoryID, page.StorySlug)
 			filename := "Text/" + id
-			file, err := fs.Create(fmt.Sprintf("%s/%s", ebookDir, filename))
-			if err != nil {
-				return errors.Wrapf(err, "creating target file for %s", filename)
-			}
 
 			ed.wordCount += page.WordCount()
 
-			w := bufio.NewWriter(file)
-			w.WriteString(`<?xml version="1.0" encoding="utf-8" standalone="no"?>`)
-			err = storyPageTmpl.Execute(w, page)
-			if err != nil {
-				return errors.Wrapf(err, "writing target file for %s", filename)
+			var headings []contentEntry
+			if v.TOCFromHeadings != "" {
+				nest := v.TOCNest
+				if nest == 0 {
+					// default value of 1, see RenderInTocNCX
+					nest = 1
+				}
+				headings, err = headingTOC(page, v.TOCFromHeadings, filename, nest+1)
+				if err != nil {
+					return nil, errors.Wrapf(err, "story #%s", v.Story.ID)
+				}
+			}
+
+			data := storyPageData{WhateleyPage: page}
+			if ed.wantsAttribution(&v) {
+				data.Attribution, err = renderAttribution(ed.template("attribution.html"), page)
+				if err != nil {
+					return nil, errors.Wrapf(err, "writing attribution for %s", filename)
+				}
 			}
-			err = w.Flush()
+
+			doc, err := renderPage(ed.template("part.html"), data)
 			if err != nil {
-				return errors.Wrapf(err, "writing target file for %s", filename)
+				return nil, errors.Wrapf(err, "writing target file for %s", filename)
 			}
 
-			ed.files = append(ed.files, contentEntry{
+			entry := contentEntry{
 				Filename:    filename,
 				Id:          id,
 				ContentType: "application/xhtml+xml",
 				TOC:         v.TOC,
 				TOCNest:     v.TOCNest,
-			})
+				StoryID:     page.StoryID,
+			}
+			if !bodyMarked {
+				entry.Landmark = "bodymatter"
+				bodyMarked = true
+			}
+			parts = append(parts, textPart{entry, doc})
+			for _, h := range headings {
+				parts = append(parts, textPart{h, nil})
+			}
 		} else if v.IsAnchorEntry() {
 			// TOC entry to an anchor on existing page
-			ed.files = append(ed.files, contentEntry{
+			parts = append(parts, textPart{contentEntry{
 				Filename: v.TOCPage,
 				TOC:      v.TOC,
 				TOCNest:  v.TOCNest,
-			})
+			}, nil})
 		} else {
-			return errors.Errorf("bad epub definition file [%#v]", v)
+			return nil, errors.Errorf("bad epub definition file [%#v]", v)
+		}
+	}
+	err := ed.rewriteStoryLinks(access, parts)
+	if err != nil {
+		return nil, err
+	}
+	return parts, nil
+}
+
+func (ed *EpubDefinition) WriteText(access *client.WANetwork, fs fileCreator) error {
+	ebookDir := "OEBPS"
+
+	parts, err := ed.renderText(access)
+	if err != nil {
+		return err
+	}
+	parts = ed.splitLongParts(parts)
+	for _, v := range parts {
+		if v.doc != nil && ed.kepub {
+			kepubify(v.doc)
+		}
+		if v.doc != nil {
+			size, err := writeXHTMLFile(fs, fmt.Sprintf("%s/%s", ebookDir, v.Filename), v.doc, ed.IsEpub3())
+			if err != nil {
+				return err
+			}
+			ed.checkXHTMLSize(v.Filename, size)
 		}
+		ed.files = append(ed.files, v.contentEntry)
 	}
 	return nil
 }
@@ -681,17 +1040,59 @@ type fileCreator interface {
 	Create(name string) (io.Writer, error)
 }
 
-type panicDupesZipWriter struct {
-	*zip.Writer
-	files map[string]struct{}
+// sortedZipWriter holds the files of the epub in memory, so that they can be written to the zip
+// file in a stable order: mimetype first, as required by the OCF spec, then sorted by name.
+type sortedZipWriter struct {
+	files map[string]*bytes.Buffer
 }
 
-func (w panicDupesZipWriter) Create(name string) (io.Writer, error) {
+func (w sortedZipWriter) Create(name string) (io.Writer, error) {
 	if _, ok := w.files[name]; ok {
 		return nil, errors.Errorf("Attempt to create duplicate file name %s", name)
 	}
-	w.files[name] = struct{}{}
-	return w.Writer.Create(name)
+	buf := new(bytes.Buffer)
+	w.files[name] = buf
+	return buf, nil
+}
+
+// WriteZip writes all files to the zip file, with the given modification time.
+func (w sortedZipWriter) WriteZip(out io.Writer, modified time.Time) error {
+	if zipEpoch := time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC); modified.Before(zipEpoch) {
+		// MS-DOS timestamps start at 1980
+		modified = zipEpoch
+	}
+	names := make([]string, 0, len(w.files))
+	for k := range w.files {
+		if k != "mimetype" {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	if _, ok := w.files["mimetype"]; ok {
+		names = append([]string{"mimetype"}, names...)
+	}
+
+	zw := zip.NewWriter(out)
+	for _, name := range names {
+		hdr := &zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: modified.UTC(),
+		}
+		if name == "mimetype" {
+			// The OCF spec requires the mimetype to be stored uncompressed
+			hdr.Method = zip.Store
+		}
+		file, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return errors.Wrapf(err, "adding %s to zip", name)
+		}
+		_, err = w.files[name].WriteTo(file)
+		if err != nil {
+			return errors.Wrapf(err, "adding %s to zip", name)
+		}
+	}
+	return zw.Close()
 }
 
 type dummyFileCreator func(string) (io.Writer, error)
@@ -700,6 +1101,21 @@ func (d dummyFileCreator) Create(name string) (io.Writer, error) {
 	return d(name)
 }
 
+// prepareAll processes every story that was not already handled by Prepare.
+func (ed *EpubDefinition) prepareAll(access *client.WANetwork) error {
+	for i := range ed.Parts {
+		if ed.Parts[i].IsContentPage() {
+			_, err := ed.Parts[i].preparePage(access, ed)
+			if err != nil {
+				return errors.Wrapf(err, "preparing content for story #%s", ed.Parts[i].Story.ID)
+			}
+		}
+	}
+	ed.fillAuthor()
+	ed.siteURL = access.BaseURL().String()
+	return nil
+}
+
 func CreateEpub(ed *EpubDefinition, access *client.WANetwork, filename string) error {
 	file, err := os.Create(filename)
 	if err != nil {
@@ -709,11 +1125,7 @@ func CreateEpub(ed *EpubDefinition, access *client.WANetwork, filename string) e
 
 	ed.PrepareAssets()
 
-	_zipWriter := zip.NewWriter(file)
-	zipWriter := panicDupesZipWriter{
-		_zipWriter,
-		make(map[string]struct{}),
-	}
+	zipWriter := sortedZipWriter{make(map[string]*bytes.Buffer)}
 
 	err = ed.WriteMetaINF(zipWriter)
 	if err != nil {
@@ -723,11 +1135,23 @@ func CreateEpub(ed *EpubDefinition, access *client.WANetwork, filename string) e
 	ed.lock.Lock()
 	defer ed.lock.Unlock()
 
+	// Process every story first, so that all images in story bodies are known
+	err = ed.prepareAll(access)
+	if err != nil {
+		return err
+	}
+
 	// Download assets
 	err = ed.WriteAssets(access, zipWriter)
 	if err != nil {
 		return err
 	}
+	ed.printImageSummary(os.Stdout)
+
+	err = ed.WriteCover(zipWriter)
+	if err != nil {
+		return err
+	}
 
 	err = ed.WriteText(access, zipWriter)
 	if err != nil {
@@ -738,20 +1162,31 @@ func CreateEpub(ed *EpubDefinition, access *client.WANetwork, filename string) e
 	if err != nil {
 		return err
 	}
+	err = ed.WriteFonts(zipWriter)
+	if err != nil {
+		return err
+	}
 
 	err = ed.WriteTableOfContents(zipWriter)
 	if err != nil {
 		return err
 	}
 
+	if ed.IsEpub3() {
+		err = ed.WriteNavDocument(zipWriter)
+		if err != nil {
+			return err
+		}
+	}
+
 	err = ed.WriteContentOPF(zipWriter)
 	if err != nil {
 		return err
 	}
 
-	err = zipWriter.Close()
+	err = zipWriter.WriteZip(file, ed.buildTime())
 	if err != nil {
-		return err
+		return errors.Wrap(err, "writing output file")
 	}
 
 	fmt.Printf("Created %s.\nWord Count: %d\n", filename, ed.wordCount)
@@ -0,0 +1,94 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package ebooks
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/riking/whateley-ebooks/client"
+	"github.com/riking/whateley-ebooks/client/fakesite"
+)
+
+// demoAccess returns a client for the demo site, and a function that stops the site.
+func demoAccess(t *testing.T) (*client.WANetwork, func()) {
+	srv := httptest.NewServer(fakesite.Demo())
+	transport, err := fakesite.Redirect(srv.URL, nil)
+	if err != nil {
+		srv.Close()
+		t.Fatal(err)
+	}
+	return client.New(client.Options{Transport: transport, MaxRetries: -1}), srv.Close
+}
+
+// EPUB 3 content documents use the HTML5 DOCTYPE, and the nav lists the landmarks.
+func TestEpub3Documents(t *testing.T) {
+	access, done := demoAccess(t)
+	defer done()
+
+	dir, err := ioutil.TempDir("", "epub3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, version := range []int{2, 3} {
+		ed := &EpubDefinition{Title: "Test", Author: "Demo Author", UUID: "urn:uuid:00000000-0000-4000-8000-000000000000", EpubVersion: version}
+		part := TOCEntry{TOC: "First Day"}
+		part.Story.ID = "1"
+		ed.Parts = []TOCEntry{part}
+
+		filename := filepath.Join(dir, "test.epub")
+		err = CreateEpub(ed, access, filename)
+		if err != nil {
+			t.Fatalf("epub %d: %+v", version, err)
+		}
+
+		r, err := zip.OpenReader(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages := 0
+		for _, f := range r.File {
+			if !strings.HasPrefix(f.Name, "OEBPS/Text/") {
+				continue
+			}
+			pages++
+			page := readZipFile(t, filename, f.Name)
+			html5 := strings.Contains(page, "<!DOCTYPE html>")
+			xhtml11 := strings.Contains(page, `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.1//EN"`)
+			if version == 3 && (!html5 || xhtml11) {
+				t.Errorf("epub 3: %s does not have the HTML5 DOCTYPE:\n%.200s", f.Name, page)
+			} else if version == 2 && !xhtml11 {
+				t.Errorf("epub 2: %s does not have the XHTML 1.1 DOCTYPE:\n%.200s", f.Name, page)
+			}
+		}
+		r.Close()
+		if pages == 0 {
+			t.Errorf("epub %d: no content documents", version)
+		}
+
+		if version == 3 {
+			nav := readZipFile(t, filename, "OEBPS/nav.xhtml")
+			if !strings.Contains(nav, "<!DOCTYPE html>") {
+				t.Errorf("nav has no HTML5 DOCTYPE:\n%s", nav)
+			}
+			i := strings.Index(nav, `epub:type="landmarks"`)
+			if i == -1 {
+				t.Fatalf("nav has no landmarks:\n%s", nav)
+			}
+			landmarks := nav[i:]
+			for _, want := range []string{`epub:type="toc" href="nav.xhtml"`, `epub:type="bodymatter" href="Text/`} {
+				if !strings.Contains(landmarks, want) {
+					t.Errorf("landmarks do not contain %s:\n%s", want, landmarks)
+				}
+			}
+		}
+	}
+}
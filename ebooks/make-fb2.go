@@ -0,0 +1,440 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package ebooks
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+
+	"github.com/riking/whateley-ebooks/client"
+)
+
+// FictionBook 2 output.
+//
+// Parts become nested <section>s following toc-nest. Cover pages become the title of their section,
+// and the stories that follow are nested inside. Anchor entries have no equivalent in FB2 and are skipped.
+
+// fb2Converter turns processed story markup into FB2 body elements.
+type fb2Converter struct {
+	out bytes.Buffer
+	// binary id for each image in OEBPS/Images, by file name
+	images map[string]string
+	used   map[string]bool
+	inCite bool
+}
+
+// fb2Para collects the inline content of one <p>.
+type fb2Para struct {
+	buf     bytes.Buffer
+	hasText bool
+	// open inline elements: the element name and the full start tag
+	open [][2]string
+}
+
+func (p *fb2Para) start(name, tag string) {
+	p.open = append(p.open, [2]string{name, tag})
+	p.buf.WriteString(tag)
+}
+
+func (p *fb2Para) end() {
+	p.buf.WriteString("</" + p.open[len(p.open)-1][0] + ">")
+	p.open = p.open[:len(p.open)-1]
+}
+
+// flush writes the paragraph, if it has any text. Inline elements that are still open are closed,
+// and opened again for the next paragraph.
+func (c *fb2Converter) flush(p *fb2Para, element string) {
+	if p.hasText {
+		for i := len(p.open) - 1; i >= 0; i-- {
+			p.buf.WriteString("</" + p.open[i][0] + ">")
+		}
+		fmt.Fprintf(&c.out, "<%s>%s</%s>", element, strings.TrimSpace(p.buf.String()), element)
+	}
+	p.buf.Reset()
+	p.hasText = false
+	for _, v := range p.open {
+		p.buf.WriteString(v[1])
+	}
+}
+
+func (c *fb2Converter) image(n *html.Node) {
+	src := attrValue(n, "src")
+	id, ok := c.images[strings.TrimPrefix(src, "../Images/")]
+	if !ok || c.inCite {
+		// images are not allowed in a <cite>
+		return
+	}
+	c.used[id] = true
+	fmt.Fprintf(&c.out, `<image l:href="#%s"/>`, xmlEscape(id))
+}
+
+func (c *fb2Converter) blocks(n *html.Node) {
+	p := new(fb2Para)
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		if ch.Type == html.ElementNode && textBlockElements[ch.Data] {
+			c.flush(p, "p")
+			c.block(ch)
+		} else {
+			c.inline(p, ch)
+		}
+	}
+	c.flush(p, "p")
+}
+
+func (c *fb2Converter) block(n *html.Node) {
+	switch {
+	case n.Data == "hr":
+		// scene break
+		c.out.WriteString("<empty-line/>")
+	case isQuoteBlock(n) && !c.inCite:
+		start := c.out.Len()
+		c.out.WriteString("<cite>")
+		c.inCite = true
+		c.blocks(n)
+		c.inCite = false
+		if c.out.Len() == start+len("<cite>") {
+			// a <cite> must not be empty
+			c.out.Truncate(start)
+		} else {
+			c.out.WriteString("</cite>")
+		}
+	case n.Data == "ul" || n.Data == "ol":
+		num := 0
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			if ch.Type != html.ElementNode || ch.Data != "li" {
+				continue
+			}
+			num++
+			p := new(fb2Para)
+			if n.Data == "ol" {
+				fmt.Fprintf(&p.buf, "%d. ", num)
+			} else {
+				p.buf.WriteString("• ")
+			}
+			c.inline(p, ch)
+			c.flush(p, "p")
+		}
+	case len(n.Data) == 2 && n.Data[0] == 'h' && n.Data[1] >= '1' && n.Data[1] <= '6':
+		p := new(fb2Para)
+		c.inline(p, n)
+		c.flush(p, "subtitle")
+	default:
+		c.blocks(n)
+	}
+}
+
+func (c *fb2Converter) inline(p *fb2Para, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		text := textSpaceRegexp.ReplaceAllString(n.Data, " ")
+		if strings.TrimSpace(text) != "" {
+			p.hasText = true
+		}
+		p.buf.WriteString(xmlEscape(text))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	var name, tag string
+	switch {
+	case n.Data == "script" || n.Data == "style":
+		return
+	case n.Data == "br":
+		// FB2 has no line breaks inside a paragraph
+		c.flush(p, "p")
+		return
+	case n.Data == "img":
+		c.flush(p, "p")
+		c.image(n)
+		return
+	case n.Data == "em" || n.Data == "i" || n.Data == "cite" || hasClass(n, "italic"):
+		name = "emphasis"
+	case n.Data == "strong" || n.Data == "b" || hasClass(n, "bold"):
+		name = "strong"
+	case n.Data == "s" || n.Data == "strike" || n.Data == "del" || hasClass(n, "strike"):
+		name = "strikethrough"
+	case n.Data == "sub" || n.Data == "sup":
+		name = n.Data
+	case n.Data == "code" || n.Data == "tt" || hasClass(n, "monospace"):
+		name = "code"
+	case n.Data == "a" && strings.Contains(attrValue(n, "href"), "://"):
+		name = "a"
+		tag = fmt.Sprintf(`<a l:href="%s">`, xmlEscape(attrValue(n, "href")))
+	}
+	if name != "" {
+		if tag == "" {
+			tag = "<" + name + ">"
+		}
+		p.start(name, tag)
+	}
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		c.inline(p, ch)
+	}
+	if name != "" {
+		p.end()
+	}
+}
+
+// fb2Section is an open <section> while writing the body.
+type fb2Section struct {
+	level int
+	// story sections hold paragraphs, so they cannot have child sections
+	content bool
+	empty   bool
+}
+
+type fb2Body struct {
+	c     *fb2Converter
+	stack []fb2Section
+}
+
+func (b *fb2Body) close() {
+	if b.stack[len(b.stack)-1].empty {
+		// a <section> must have some content
+		b.c.out.WriteString("<empty-line/>")
+	}
+	b.c.out.WriteString("</section>")
+	b.stack = b.stack[:len(b.stack)-1]
+	if len(b.stack) > 0 {
+		b.stack[len(b.stack)-1].empty = false
+	}
+}
+
+// open starts a section at the given toc-nest level. A level of 0 continues at the current level.
+func (b *fb2Body) open(level int, content bool) {
+	depth := len(b.stack)
+	if level == 0 {
+		level = depth + 1
+		if depth > 0 && b.stack[depth-1].content {
+			level = depth
+		}
+	}
+	if level > depth+1 {
+		level = depth + 1
+	}
+	if level == depth+1 && depth > 0 && b.stack[depth-1].content {
+		level = depth
+	}
+	for len(b.stack) >= level && len(b.stack) > 0 {
+		b.close()
+	}
+	b.c.out.WriteString("<section>")
+	b.stack = append(b.stack, fb2Section{level: level, content: content, empty: true})
+}
+
+func (b *fb2Body) title(paragraphs ...string) {
+	b.c.out.WriteString("<title>")
+	for _, v := range paragraphs {
+		fmt.Fprintf(&b.c.out, "<p>%s</p>", xmlEscape(v))
+	}
+	b.c.out.WriteString("</title>")
+}
+
+// fb2Author writes an author. Names on the site are pen names, so they go in <nickname>.
+func fb2Author(name string) string {
+	return fmt.Sprintf("<author><nickname>%s</nickname></author>", xmlEscape(name))
+}
+
+// fb2DocumentID is the <id> of the document: the UUID of the definition, or one derived from the
+// title and stories, so that rebuilding the book keeps the id.
+func (ed *EpubDefinition) fb2DocumentID() string {
+	if ed.UUID != "" {
+		return ed.UUID
+	}
+	h := sha1.New()
+	io.WriteString(h, ed.Title)
+	for _, v := range ed.Parts {
+		io.WriteString(h, "\x00"+v.Story.ID)
+	}
+	sum := h.Sum(nil)
+	// a version 5 (name-based) UUID
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// renderFB2 writes the book as a FictionBook 2 document.
+func (ed *EpubDefinition) renderFB2(access *client.WANetwork, images []*bundledImage, w *bufio.Writer) error {
+	c := &fb2Converter{
+		images: make(map[string]string),
+		used:   make(map[string]bool),
+	}
+	binaries := make(map[string]*bundledImage)
+	for _, v := range images {
+		id := imageID(v.Target)
+		c.images[v.Target] = id
+		binaries[id] = v
+	}
+
+	body := &fb2Body{c: c}
+	c.out.WriteString("<body>")
+	body.title(ed.Title)
+	for i := range ed.Parts {
+		v := &ed.Parts[i]
+		if v.IsCoverPage() && !v.fixCvr {
+			doc, err := html.Parse(strings.NewReader(v.CoverPage))
+			if err != nil {
+				return errors.Wrap(err, "parsing coverpage")
+			}
+			level := v.TOCNest
+			if level == 0 {
+				level = 1
+			}
+			body.open(level, false)
+			// the text of the cover page becomes the section title
+			var lines []string
+			t := textRenderer{format: PlainText}
+			t.renderBlock(findBody(doc), "")
+			for _, para := range t.out {
+				lines = append(lines, strings.Split(para, "\n")...)
+			}
+			if len(lines) == 0 && v.TOC != "" {
+				lines = []string{v.TOC}
+			}
+			body.title(lines...)
+		} else if v.IsContentPage() {
+			page, err := v.preparePage(access, ed)
+			if err != nil {
+				return errors.Wrapf(err, "preparing content for story #%s", v.Story.ID)
+			}
+			level := v.TOCNest
+			if level == 0 && v.TOC != "" {
+				level = 1
+			}
+			body.open(level, true)
+			title := v.TOC
+			if title == "" {
+				title = page.Title()
+			}
+			body.title(title)
+			start := c.out.Len()
+			for _, n := range page.StoryBodySelection().Nodes {
+				c.blocks(n)
+			}
+			if c.out.Len() != start {
+				body.stack[len(body.stack)-1].empty = false
+			}
+		}
+	}
+	for len(body.stack) > 0 {
+		body.close()
+	}
+	c.out.WriteString("</body>")
+
+	var coverID string
+	if ed.Cover != nil && ed.Cover.Asset != "" {
+		// The generated SVG cover is not used, as FB2 readers only support raster images
+		coverID = imageID(ed.Cover.Asset)
+		if _, ok := binaries[coverID]; !ok {
+			return errors.Errorf("cover asset %s is not in the assets list", ed.Cover.Asset)
+		}
+		c.used[coverID] = true
+	}
+	creator, err := ed.Creator()
+	if err != nil {
+		return err
+	}
+
+	w.WriteString(`<?xml version="1.0" encoding="utf-8"?>` + "\n")
+	w.WriteString(`<FictionBook xmlns="http://www.gribuser.ru/xml/fictionbook/2.0" xmlns:l="http://www.w3.org/1999/xlink">`)
+	w.WriteString("<description><title-info><genre>sf</genre>")
+	w.WriteString(fb2Author(ed.Author))
+	fmt.Fprintf(w, "<book-title>%s</book-title>", xmlEscape(ed.Title))
+	if ed.Description != "" {
+		fmt.Fprintf(w, "<annotation><p>%s</p></annotation>", xmlEscape(ed.Description))
+	}
+	if subjects := ed.Subjects(); len(subjects) > 0 {
+		fmt.Fprintf(w, "<keywords>%s</keywords>", xmlEscape(strings.Join(subjects, ", ")))
+	}
+	if first, _ := ed.publishRange(); !first.IsZero() {
+		fmt.Fprintf(w, `<date value="%s">%s</date>`, first.Format(metadataDateFmt), first.Format("2 January 2006"))
+	}
+	if coverID != "" {
+		fmt.Fprintf(w, `<coverpage><image l:href="#%s"/></coverpage>`, coverID)
+	}
+	fmt.Fprintf(w, "<lang>%s</lang>", xmlEscape(ed.Lang()))
+	if ed.Series != "" && ed.SeriesIndex != 0 {
+		fmt.Fprintf(w, `<sequence name="%s" number="%d"/>`, xmlEscape(ed.Series), int(ed.SeriesIndex))
+	} else if ed.Series != "" {
+		fmt.Fprintf(w, `<sequence name="%s"/>`, xmlEscape(ed.Series))
+	}
+	w.WriteString("</title-info><document-info>")
+	w.WriteString(fb2Author(creator))
+	w.WriteString("<program-used>https://github.com/riking/whateley-ebooks</program-used>")
+	t := ed.buildTime()
+	fmt.Fprintf(w, `<date value="%s">%s</date>`, t.Format("2006-01-02"), t.Format("2 January 2006"))
+	fmt.Fprintf(w, "<id>%s</id><version>1.0</version>", xmlEscape(ed.fb2DocumentID()))
+	w.WriteString("</document-info>")
+	if ed.Publisher != "" {
+		fmt.Fprintf(w, "<publish-info><publisher>%s</publisher></publish-info>", xmlEscape(ed.Publisher))
+	}
+	w.WriteString("</description>\n")
+	c.out.WriteTo(w)
+	w.WriteString("\n")
+
+	for _, v := range images {
+		id := imageID(v.Target)
+		if !c.used[id] {
+			continue
+		}
+		contentType := v.contentType
+		if contentType == "" {
+			contentType = mime.TypeByExtension(path.Ext(v.Target))
+		}
+		fmt.Fprintf(w, `<binary id="%s" content-type="%s">%s</binary>`+"\n",
+			id, xmlEscape(contentType), base64.StdEncoding.EncodeToString(v.body))
+	}
+	w.WriteString("</FictionBook>\n")
+	return nil
+}
+
+// CreateFB2 writes the book as a FictionBook 2 (.fb2) file.
+func CreateFB2(ed *EpubDefinition, access *client.WANetwork, filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return errors.Wrap(err, "could not create output file")
+	}
+	defer file.Close()
+
+	ed.PrepareAssets()
+
+	ed.lock.Lock()
+	defer ed.lock.Unlock()
+
+	err = ed.prepareAll(access)
+	if err != nil {
+		return err
+	}
+
+	images, err := ed.collectImages(access)
+	if err != nil {
+		return err
+	}
+	ed.printImageSummary(os.Stdout)
+
+	w := bufio.NewWriter(file)
+	err = ed.renderFB2(access, images, w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		return errors.Wrapf(err, "writing %s", filename)
+	}
+
+	fmt.Printf("Created %s.\n", filename)
+	return nil
+}
@@ -0,0 +1,168 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package ebooks
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestFB2Sections(t *testing.T) {
+	type step struct {
+		level   int
+		content bool
+	}
+	tests := []struct {
+		name  string
+		steps []step
+		out   string
+	}{
+		{"flat stories", []step{{0, true}, {0, true}}, `<section>S</section><section>S</section>`},
+		{"cover with stories", []step{{1, false}, {2, true}, {2, true}},
+			`<section>S<section>S</section><section>S</section></section>`},
+		{"story after a nested story", []step{{1, false}, {2, true}, {0, true}},
+			`<section>S<section>S</section><section>S</section></section>`},
+		{"stories cannot hold sections", []step{{1, true}, {2, true}},
+			`<section>S</section><section>S</section>`},
+		{"level skips", []step{{1, false}, {3, true}},
+			`<section>S<section>S</section></section>`},
+		{"second cover", []step{{1, false}, {2, true}, {1, false}, {2, true}},
+			`<section>S<section>S</section></section><section>S<section>S</section></section>`},
+		{"empty cover", []step{{1, false}, {1, false}},
+			`<section>S<empty-line/></section><section>S<empty-line/></section>`},
+	}
+	for _, tt := range tests {
+		c := &fb2Converter{}
+		body := &fb2Body{c: c}
+		for _, s := range tt.steps {
+			body.open(s.level, s.content)
+			c.out.WriteString("S")
+			if s.content {
+				body.stack[len(body.stack)-1].empty = false
+			}
+		}
+		for len(body.stack) > 0 {
+			body.close()
+		}
+		if c.out.String() != tt.out {
+			t.Errorf("%s:\n  got: %s\n  exp: %s", tt.name, c.out.String(), tt.out)
+		}
+	}
+}
+
+func TestFB2Blocks(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		out  string
+	}{
+		{"paragraphs", `<p>One <em>two</em></p><p>Three &amp; four</p>`, `<p>One <emphasis>two</emphasis></p><p>Three &amp; four</p>`},
+		{"scene break", `<p>a</p><hr><p>b</p>`, `<p>a</p><empty-line/><p>b</p>`},
+		{"line break inside emphasis", `<p><em>a<br>b</em></p>`, `<p><emphasis>a</emphasis></p><p><emphasis>b</emphasis></p>`},
+		{"heading", `<h3>Part 1</h3>`, `<subtitle>Part 1</subtitle>`},
+		{"quote", `<blockquote><p>quoted</p></blockquote>`, `<cite><p>quoted</p></cite>`},
+		{"nested quote", `<blockquote><p>a</p><div class="lyrics"><p>b</p></div></blockquote>`, `<cite><p>a</p><p>b</p></cite>`},
+		{"empty quote", `<blockquote><p> </p></blockquote><p>x</p>`, `<p>x</p>`},
+		{"image in quote", `<blockquote><p><img src="../Images/a.png"> text</p></blockquote>`, `<cite><p>text</p></cite>`},
+		{"image", `<p><img src="../Images/a.png"></p>`, `<image l:href="#img-a-png"/>`},
+		{"unknown image", `<p><img src="http://example.com/b.png"></p>`, ``},
+		{"external link", `<p><a href="http://example.com/?a=1&amp;b=2">x</a></p>`, `<p><a l:href="http://example.com/?a=1&amp;b=2">x</a></p>`},
+		{"list", `<ol><li>one</li><li>two</li></ol>`, `<p>1. one</p><p>2. two</p>`},
+	}
+	for _, tt := range tests {
+		doc, err := html.Parse(strings.NewReader("<body>" + tt.in + "</body>"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := &fb2Converter{
+			images: map[string]string{"a.png": imageID("a.png")},
+			used:   make(map[string]bool),
+		}
+		c.blocks(findBody(doc))
+		if c.out.String() != tt.out {
+			t.Errorf("%s:\n  in:  %s\n  got: %s\n  exp: %s", tt.name, tt.in, c.out.String(), tt.out)
+		}
+	}
+}
+
+// A book from the demo site is well-formed, with the publisher in <publish-info> and an id.
+func TestCreateFB2(t *testing.T) {
+	access, done := demoAccess(t)
+	defer done()
+
+	dir, err := ioutil.TempDir("", "fb2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ed := &EpubDefinition{Title: "Test", Author: "Demo Author", Publisher: "Whateley Academy",
+		Parts: []TOCEntry{{TOC: "Book One", CoverPage: "<p>Book One</p>", TOCNest: 1}}}
+	for _, id := range []string{"1", "2"} {
+		part := TOCEntry{TOCNest: 2}
+		part.Story.ID = id
+		ed.Parts = append(ed.Parts, part)
+	}
+
+	filename := filepath.Join(dir, "test.fb2")
+	err = CreateFB2(ed, access, filename)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var book struct {
+		Description struct {
+			DocumentInfo struct {
+				ID        string   `xml:"id"`
+				Publisher []string `xml:"publisher"`
+			} `xml:"document-info"`
+			PublishInfo struct {
+				Publisher string `xml:"publisher"`
+			} `xml:"publish-info"`
+		} `xml:"description"`
+		Body struct {
+			Sections []struct {
+				Title    string `xml:"title>p"`
+				Sections []struct {
+					Title string `xml:"title>p"`
+				} `xml:"section"`
+			} `xml:"section"`
+		} `xml:"body"`
+		Binaries []struct {
+			ID string `xml:"id,attr"`
+		} `xml:"binary"`
+	}
+	err = xml.Unmarshal(b, &book)
+	if err != nil {
+		t.Fatalf("not well-formed: %v\n%s", err, b)
+	}
+	info := book.Description
+	if info.DocumentInfo.ID == "" || len(info.DocumentInfo.Publisher) != 0 || info.PublishInfo.Publisher != "Whateley Academy" {
+		t.Errorf("wrong document info: %+v", info)
+	}
+	sections := book.Body.Sections
+	if len(sections) != 1 || sections[0].Title != "Book One" || len(sections[0].Sections) != 2 ||
+		sections[0].Sections[0].Title != "First Day" || sections[0].Sections[1].Title != "Second Day" {
+		t.Errorf("wrong sections: %+v", sections)
+	}
+	if len(book.Binaries) != 1 || !strings.HasPrefix(book.Binaries[0].ID, "img-demo-") {
+		t.Errorf("wrong binaries: %+v", book.Binaries)
+	}
+
+	// rebuilding keeps the id
+	ed2 := &EpubDefinition{Title: ed.Title, Parts: ed.Parts}
+	if ed2.fb2DocumentID() != info.DocumentInfo.ID {
+		t.Errorf("document id is not stable: %s != %s", ed2.fb2DocumentID(), info.DocumentInfo.ID)
+	}
+}
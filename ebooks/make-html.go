@@ -0,0 +1,244 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package ebooks
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"mime"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/riking/whateley-ebooks/client"
+)
+
+var bookHTMLTmpl = template.Must(template.New("book-html").Parse(string(MustAsset("book.html"))))
+
+// htmlSection is one page of the book, inside the single HTML file.
+type htmlSection struct {
+	ID   string
+	Body template.HTML
+}
+
+// sectionID is the id of the <section> that holds the given book file, e.g. "34-whisper-one" for
+// "Text/34-whisper-one.html".
+func sectionID(filename string) string {
+	return strings.TrimSuffix(path.Base(filename), path.Ext(filename))
+}
+
+// dataURI embeds an image in the page.
+func dataURI(img *bundledImage) template.URL {
+	contentType := img.contentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(img.Target))
+	}
+	return template.URL(fmt.Sprintf("data:%s;base64,%s", contentType, base64.StdEncoding.EncodeToString(img.body)))
+}
+
+// singleFileLinks rewrites the links, ids, and images of one page for the single HTML file.
+// Each page becomes a <section>, so ids are prefixed with the section id to keep them unique.
+type singleFileLinks struct {
+	sections map[string]bool
+	images   map[string]*bundledImage
+}
+
+// href maps a link inside the book, relative to the Text/ directory, to a fragment link.
+// section is the page the link is on, or "" for links outside of any page, such as the TOC.
+func (l *singleFileLinks) href(section, href string) (string, bool) {
+	if strings.HasPrefix(href, "#") {
+		if section == "" {
+			return "", false
+		}
+		return fmt.Sprintf("#%s-%s", section, href[1:]), true
+	}
+	if strings.Contains(href, ":") {
+		// absolute URL
+		return "", false
+	}
+	target, frag := href, ""
+	if idx := strings.IndexByte(href, '#'); idx != -1 {
+		target, frag = href[:idx], href[idx+1:]
+	}
+	targetSection := sectionID(target)
+	if !l.sections[targetSection] {
+		return "", false
+	}
+	if frag == "" {
+		return "#" + targetSection, true
+	}
+	return fmt.Sprintf("#%s-%s", targetSection, frag), true
+}
+
+func (l *singleFileLinks) rewrite(section string, n *html.Node) {
+	if n.Type == html.ElementNode {
+		for i := range n.Attr {
+			a := &n.Attr[i]
+			switch {
+			case a.Key == "id" || (a.Key == "name" && n.DataAtom == atom.A):
+				a.Val = fmt.Sprintf("%s-%s", section, a.Val)
+			case a.Key == "href":
+				if v, ok := l.href(section, a.Val); ok {
+					a.Val = v
+				}
+			case a.Key == "src" && strings.HasPrefix(a.Val, "../Images/"):
+				if img, ok := l.images[strings.TrimPrefix(a.Val, "../Images/")]; ok {
+					a.Val = string(dataURI(img))
+				}
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		l.rewrite(section, c)
+	}
+}
+
+func findBody(n *html.Node) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == atom.Body {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if b := findBody(c); b != nil {
+			return b
+		}
+	}
+	return nil
+}
+
+// renderHTML writes the whole book as one self-contained HTML page.
+func (ed *EpubDefinition) renderHTML(parts []textPart, images []*bundledImage, w *bufio.Writer) error {
+	links := singleFileLinks{
+		sections: make(map[string]bool),
+		images:   make(map[string]*bundledImage),
+	}
+	for _, v := range images {
+		links.images[v.Target] = v
+	}
+	for _, v := range parts {
+		if v.doc != nil {
+			links.sections[sectionID(v.Filename)] = true
+		}
+	}
+	if ed.Cover != nil {
+		// The cover page is replaced by the cover image at the top, which gets its id
+		links.sections[sectionID(coverPageFile)] = true
+	}
+
+	var sections []htmlSection
+	var toc contentEntries
+	for _, v := range parts {
+		entry := v.contentEntry
+		// TOC links are relative to OEBPS/, page links to OEBPS/Text/
+		if href, ok := links.href("", strings.TrimPrefix(entry.Filename, "Text/")); ok {
+			entry.Filename = href
+		}
+		toc = append(toc, entry)
+		if v.doc == nil {
+			continue
+		}
+
+		id := sectionID(v.Filename)
+		body := findBody(v.doc)
+		if body == nil {
+			return errors.Errorf("no <body> in %s", v.Filename)
+		}
+		links.rewrite(id, body)
+		var buf bytes.Buffer
+		for c := body.FirstChild; c != nil; c = c.NextSibling {
+			err := html.Render(&buf, c)
+			if err != nil {
+				return errors.Wrapf(err, "rendering %s", v.Filename)
+			}
+		}
+		sections = append(sections, htmlSection{ID: id, Body: template.HTML(buf.String())})
+	}
+
+	var tocBuf bytes.Buffer
+	toc.RenderInNavXHTML(&tocBuf)
+
+	var cover template.URL
+	if ed.Cover != nil && ed.Cover.Asset != "" {
+		img, ok := links.images[ed.Cover.Asset]
+		if !ok {
+			return errors.Errorf("cover asset %s is not in the assets list", ed.Cover.Asset)
+		}
+		cover = dataURI(img)
+	} else if ed.Cover != nil {
+		cover = dataURI(&bundledImage{body: ed.RenderCoverSVG(), contentType: "image/svg+xml"})
+	}
+
+	css, err := ed.styleSheet(true)
+	if err != nil {
+		return err
+	}
+
+	return bookHTMLTmpl.Execute(w, struct {
+		Title    string
+		Lang     string
+		CSS      template.CSS
+		Cover    template.URL
+		CoverID  string
+		TOC      template.HTML
+		Sections []htmlSection
+	}{
+		Title:    ed.Title,
+		Lang:     ed.Lang(),
+		CSS:      template.CSS(css),
+		Cover:    cover,
+		CoverID:  sectionID(coverPageFile),
+		TOC:      template.HTML(tocBuf.String()),
+		Sections: sections,
+	})
+}
+
+// CreateHTML writes the book as a single HTML file, with the stylesheet and images embedded, for
+// reading in a web browser.
+func CreateHTML(ed *EpubDefinition, access *client.WANetwork, filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return errors.Wrap(err, "could not create output file")
+	}
+	defer file.Close()
+
+	ed.PrepareAssets()
+
+	ed.lock.Lock()
+	defer ed.lock.Unlock()
+
+	err = ed.prepareAll(access)
+	if err != nil {
+		return err
+	}
+
+	images, err := ed.collectImages(access)
+	if err != nil {
+		return err
+	}
+	ed.printImageSummary(os.Stdout)
+
+	parts, err := ed.renderText(access)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(file)
+	err = ed.renderHTML(parts, images, w)
+	if err != nil {
+		return errors.Wrapf(err, "writing %s", filename)
+	}
+	err = w.Flush()
+	if err != nil {
+		return errors.Wrapf(err, "writing %s", filename)
+	}
+
+	fmt.Printf("Created %s.\nWord Count: %d\n", filename, ed.wordCount)
+	return nil
+}
@@ -0,0 +1,111 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package ebooks
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func testSingleFileLinks() *singleFileLinks {
+	return &singleFileLinks{
+		sections: map[string]bool{"34-whisper-one": true, "35-part-two": true, "Cover": true},
+		images: map[string]*bundledImage{
+			"pic-0a0b0c0d.png": {Target: "pic-0a0b0c0d.png", body: []byte("PNG"), contentType: "image/png"},
+		},
+	}
+}
+
+func TestSingleFileHref(t *testing.T) {
+	l := testSingleFileLinks()
+	tests := []struct {
+		section string
+		in      string
+		out     string
+		ok      bool
+	}{
+		{"34-whisper-one", "#note1", "#34-whisper-one-note1", true},
+		{"34-whisper-one", "35-part-two.html", "#35-part-two", true},
+		{"34-whisper-one", "35-part-two.html#ch2", "#35-part-two-ch2", true},
+		{"34-whisper-one", "Cover.html", "#Cover", true},
+		{"34-whisper-one", "99-not-in-book.html", "", false},
+		{"34-whisper-one", "http://whateleyacademy.net/index.php/original-timeline/99-foo", "", false},
+		{"34-whisper-one", "mailto:someone@example.com", "", false},
+		// links from the TOC are not on any page
+		{"", "34-whisper-one.html#h1", "#34-whisper-one-h1", true},
+		{"", "#toc", "", false},
+	}
+	for _, tt := range tests {
+		out, ok := l.href(tt.section, tt.in)
+		if out != tt.out || ok != tt.ok {
+			t.Errorf("href(%q, %q) = %q, %v; expected %q, %v", tt.section, tt.in, out, ok, tt.out, tt.ok)
+		}
+	}
+}
+
+func TestSingleFileRewrite(t *testing.T) {
+	in := `<h2 id="ch1">One</h2><a name="old"></a><p id="p1"><a href="#ch1">up</a> ` +
+		`<a href="35-part-two.html#ch2">next</a> <a href="http://example.com/">out</a> ` +
+		`<img src="../Images/pic-0a0b0c0d.png" alt=""/><img src="../Images/missing.png" alt=""/></p>`
+	out := `<h2 id="34-whisper-one-ch1">One</h2><a name="34-whisper-one-old"></a><p id="34-whisper-one-p1"><a href="#34-whisper-one-ch1">up</a> ` +
+		`<a href="#35-part-two-ch2">next</a> <a href="http://example.com/">out</a> ` +
+		`<img src="data:image/png;base64,UE5H" alt=""/><img src="../Images/missing.png" alt=""/></p>`
+
+	doc, err := html.Parse(strings.NewReader("<body>" + in + "</body>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := findBody(doc)
+	testSingleFileLinks().rewrite("34-whisper-one", body)
+	var buf bytes.Buffer
+	for c := body.FirstChild; c != nil; c = c.NextSibling {
+		html.Render(&buf, c)
+	}
+	if buf.String() != out {
+		t.Errorf("rewrite:\n  got: %s\n  exp: %s", buf.String(), out)
+	}
+}
+
+// The TOC and the cover of the single HTML file link to sections of the page.
+func TestRenderHTML(t *testing.T) {
+	page := func(body string) *html.Node {
+		doc, err := html.Parse(strings.NewReader("<html><head><title>x</title></head><body>" + body + "</body></html>"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return doc
+	}
+	ed := &EpubDefinition{Title: "Test", Cover: &CoverDefinition{}}
+	parts := []textPart{
+		{contentEntry{Filename: "Text/34-whisper-one.html", TOC: "Whisper"}, page(`<h2 id="h1">One</h2><a href="Cover.html">cover</a>`)},
+		{contentEntry{Filename: "Text/34-whisper-one.html#h1", TOC: "Chapter One", TOCNest: 1}, nil},
+		{contentEntry{Filename: "Text/Cover.html", TOC: "Cover"}, nil},
+	}
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	err := ed.renderHTML(parts, nil, w)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	w.Flush()
+	out := buf.String()
+	for _, want := range []string{
+		`<div class="cover-image" id="Cover">`,
+		`<section class="part" id="34-whisper-one">`,
+		`<h2 id="34-whisper-one-h1">One</h2>`,
+		`href="#34-whisper-one-h1"`,
+		`<a href="#Cover">cover</a>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %s:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Cover.html") {
+		t.Errorf("output still links to Cover.html:\n%s", out)
+	}
+}
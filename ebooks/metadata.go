@@ -0,0 +1,113 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package ebooks
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/riking/whateley-ebooks/client"
+)
+
+// Metadata for the package document, so that Calibre and device libraries can sort and filter.
+
+const metadataDateFmt = "2006-01-02"
+
+// Lang is the language of the book, English unless the definition says otherwise.
+func (ed *EpubDefinition) Lang() string {
+	if ed.Language == "" {
+		return "en"
+	}
+	return ed.Language
+}
+
+// SeriesPosition is SeriesIndex formatted for calibre:series_index, or empty if not set.
+func (ed *EpubDefinition) SeriesPosition() string {
+	if ed.SeriesIndex == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(ed.SeriesIndex, 'f', -1, 64)
+}
+
+// storyPages returns the prepared stories of the book in order.
+func (ed *EpubDefinition) storyPages() []*client.WhateleyPage {
+	var pages []*client.WhateleyPage
+	for _, v := range ed.Parts {
+		if v.Story.page != nil {
+			pages = append(pages, v.Story.page)
+		}
+	}
+	return pages
+}
+
+// Subjects are the site tags of all stories in the book, sorted.
+func (ed *EpubDefinition) Subjects() []string {
+	seen := make(map[string]bool)
+	var subjects []string
+	for _, page := range ed.storyPages() {
+		for _, tag := range page.Tags() {
+			if tag.Name == "" || seen[tag.Name] {
+				continue
+			}
+			seen[tag.Name] = true
+			subjects = append(subjects, tag.Name)
+		}
+	}
+	sort.Strings(subjects)
+	return subjects
+}
+
+// Source is the URL of the story for books with only one story.
+func (ed *EpubDefinition) Source() string {
+	pages := ed.storyPages()
+	if len(pages) != 1 {
+		return ""
+	}
+	return pages[0].URL()
+}
+
+// SiteURL is the address of the site the stories were downloaded from, for the about page.
+func (ed *EpubDefinition) SiteURL() string {
+	if ed.siteURL == "" {
+		return client.DefaultBaseURL
+	}
+	return ed.siteURL
+}
+
+// publishRange returns the earliest and latest publish dates of the stories.
+func (ed *EpubDefinition) publishRange() (first, last time.Time) {
+	for _, page := range ed.storyPages() {
+		t, err := page.PublishDate()
+		if err != nil {
+			// dates are optional, the attribution header reports missing ones
+			continue
+		}
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+		if last.IsZero() || t.After(last) {
+			last = t
+		}
+	}
+	return first, last
+}
+
+// FirstPublished is the date the first story of the book was published, or empty if unknown.
+func (ed *EpubDefinition) FirstPublished() string {
+	first, _ := ed.publishRange()
+	if first.IsZero() {
+		return ""
+	}
+	return first.Format(metadataDateFmt)
+}
+
+// LastPublished is the date the newest story of the book was published, or empty if unknown.
+func (ed *EpubDefinition) LastPublished() string {
+	_, last := ed.publishRange()
+	if last.IsZero() {
+		return ""
+	}
+	return last.Format(metadataDateFmt)
+}
@@ -0,0 +1,504 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package ebooks
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/riking/whateley-ebooks/client"
+)
+
+// Joomla-era stories are full of presentational markup: <center>, <font>, align= and inline styles.
+// modernizeLegacyMarkup turns what it can into the classes in the "legacy markup" section of
+// story.css, and records everything else in a LegacyReport.
+
+// A LegacyReport lists the inline styles and deprecated markup in a story that could not be mapped to a class.
+// The values are the number of occurrences.
+type LegacyReport struct {
+	StoryID string
+	Styles  map[string]int
+	Markup  map[string]int
+}
+
+func (r *LegacyReport) addStyle(decl string) {
+	r.Styles[decl]++
+}
+
+func (r *LegacyReport) addMarkup(desc string) {
+	r.Markup[desc]++
+}
+
+// WriteTo writes the report in a grep-friendly format, one line per unmapped item.
+func (r *LegacyReport) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	for _, k := range sortedKeys(r.Markup) {
+		fmt.Fprintf(&buf, "[legacy] #%s: unmapped markup %s (%d)\n", r.StoryID, k, r.Markup[k])
+	}
+	for _, k := range sortedKeys(r.Styles) {
+		fmt.Fprintf(&buf, "[legacy] #%s: unmapped style %q (%d)\n", r.StoryID, k, r.Styles[k])
+	}
+	return buf.WriteTo(w)
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var legacyReportOut io.Writer
+var legacyReportLock sync.Mutex
+
+// SetLegacyReport turns on the report mode of the legacy markup pass.
+// For every story processed by FixForEbook, the markup that could not be modernized is written to w.
+// Pass nil to turn the reports off again.
+func SetLegacyReport(w io.Writer) {
+	legacyReportLock.Lock()
+	legacyReportOut = w
+	legacyReportLock.Unlock()
+}
+
+func writeLegacyReport(r *LegacyReport) {
+	legacyReportLock.Lock()
+	defer legacyReportLock.Unlock()
+	if legacyReportOut != nil {
+		r.WriteTo(legacyReportOut)
+	}
+}
+
+// HTML <font size> values, 3 is the default size
+var fontSizeClasses = map[int]string{
+	1: "font-x-small",
+	2: "font-small",
+	4: "font-large",
+	5: "font-x-large",
+	6: "font-xx-large",
+	7: "font-xx-large",
+}
+
+var fontSizeKeywordClasses = map[string]string{
+	"xx-small": "font-x-small",
+	"x-small":  "font-x-small",
+	"small":    "font-small",
+	"smaller":  "font-small",
+	"medium":   "",
+	"large":    "font-large",
+	"larger":   "font-large",
+	"x-large":  "font-x-large",
+	"xx-large": "font-xx-large",
+}
+
+// fontSizeClass picks the class for a font-size in ems.
+func fontSizeClass(em float64) string {
+	switch {
+	case em < 0.7:
+		return "font-x-small"
+	case em < 0.95:
+		return "font-small"
+	case em <= 1.1:
+		return ""
+	case em < 1.35:
+		return "font-large"
+	case em < 1.75:
+		return "font-x-large"
+	default:
+		return "font-xx-large"
+	}
+}
+
+var alignClasses = map[string]string{
+	"center":  "center",
+	"middle":  "center",
+	"left":    "align-left",
+	"right":   "align-right",
+	"justify": "align-justify",
+}
+
+var floatClasses = map[string]string{
+	"left":  "float-left",
+	"right": "float-right",
+}
+
+// Typefaces that are just the site or word processor default, and are dropped without a report
+var defaultFontFamilies = []string{
+	"times", "arial", "helvetica", "verdana", "georgia", "tahoma", "calibri", "cambria",
+	"sans-serif", "serif", "inherit",
+}
+
+var monospaceFontFamilies = []string{"courier", "monospace", "consolas", "lucida console"}
+
+func fontFamilyClass(family string) (class string, ok bool) {
+	family = strings.ToLower(family)
+	for _, v := range monospaceFontFamilies {
+		if strings.Contains(family, v) {
+			return "monospace", true
+		}
+	}
+	for _, v := range defaultFontFamilies {
+		if strings.Contains(family, v) {
+			return "", true
+		}
+	}
+	return "", false
+}
+
+// Elements replaced with a <span class>
+var deprecatedInlineClasses = map[string]string{
+	"u":      "underline",
+	"s":      "strike",
+	"strike": "strike",
+	"big":    "font-large",
+	"tt":     "monospace",
+}
+
+// Elements that are removed, keeping their content
+var deprecatedUnwrap = map[string]bool{
+	"blink":   true,
+	"marquee": true,
+	"nobr":    true,
+}
+
+var blockElements = map[string]bool{
+	"address": true, "blockquote": true, "center": true, "div": true, "dl": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "hr": true, "ol": true, "p": true, "pre": true,
+	"table": true, "ul": true,
+}
+
+var ptLengthRegexp = regexp.MustCompile(`(-?\d*\.?\d+)pt\b`)
+
+// ptToEm converts every length in points to ems, taking 12pt as 1em.
+func ptToEm(s string) string {
+	return ptLengthRegexp.ReplaceAllStringFunc(s, func(m string) string {
+		v, err := strconv.ParseFloat(strings.TrimSuffix(m, "pt"), 64)
+		if err != nil {
+			return m
+		}
+		return formatEm(v / 12)
+	})
+}
+
+func formatEm(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64) + "em"
+}
+
+var cssLengthRegexp = regexp.MustCompile(`\A(-?\d*\.?\d+)(pt|px|em|%)\z`)
+
+// fontSizeToClass maps a CSS font-size value to a class. ok is false if the value is not understood.
+func fontSizeToClass(value string) (class string, ok bool) {
+	if c, ok := fontSizeKeywordClasses[value]; ok {
+		return c, true
+	}
+	m := cssLengthRegexp.FindStringSubmatch(value)
+	if m == nil {
+		return "", false
+	}
+	v, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return "", false
+	}
+	switch m[2] {
+	case "pt":
+		v = v / 12
+	case "px":
+		v = v / 16
+	case "%":
+		v = v / 100
+	}
+	return fontSizeClass(v), true
+}
+
+// styleToClasses maps the declarations of a style attribute to classes.
+// The declarations that have no class are returned as the new style attribute, with pt lengths
+// converted to ems, and are recorded in the report.
+func styleToClasses(style string, report *LegacyReport) (classes []string, rest string) {
+	var kept []string
+	for _, decl := range strings.Split(style, ";") {
+		idx := strings.IndexByte(decl, ':')
+		if idx == -1 {
+			continue
+		}
+		prop := strings.ToLower(strings.TrimSpace(decl[:idx]))
+		value := strings.TrimSpace(decl[idx+1:])
+		lValue := strings.ToLower(strings.TrimSuffix(value, "!important"))
+		lValue = strings.TrimSpace(lValue)
+
+		mapped := true
+		switch {
+		case strings.HasPrefix(prop, "mso-"):
+			// Word junk
+		case prop == "color" || prop == "background-color" || prop == "background":
+			// colors are left to the reader, see story.css
+		case prop == "text-align":
+			if c, ok := alignClasses[lValue]; ok {
+				classes = append(classes, c)
+			} else if lValue != "inherit" {
+				mapped = false
+			}
+		case prop == "font-size":
+			if c, ok := fontSizeToClass(lValue); ok {
+				if c != "" {
+					classes = append(classes, c)
+				}
+			} else {
+				mapped = false
+			}
+		case prop == "font-weight":
+			if lValue == "bold" || lValue == "bolder" || lValue == "700" || lValue == "800" || lValue == "900" {
+				classes = append(classes, "bold")
+			} else if lValue != "normal" && lValue != "400" {
+				mapped = false
+			}
+		case prop == "font-style":
+			if lValue == "italic" || lValue == "oblique" {
+				classes = append(classes, "italic")
+			} else if lValue != "normal" {
+				mapped = false
+			}
+		case prop == "text-decoration":
+			if lValue == "underline" {
+				classes = append(classes, "underline")
+			} else if lValue == "line-through" {
+				classes = append(classes, "strike")
+			} else if lValue != "none" {
+				mapped = false
+			}
+		case prop == "font-family":
+			if c, ok := fontFamilyClass(lValue); ok {
+				if c != "" {
+					classes = append(classes, c)
+				}
+			} else {
+				mapped = false
+			}
+		default:
+			mapped = false
+		}
+
+		if !mapped {
+			d := fmt.Sprintf("%s: %s", prop, ptToEm(value))
+			kept = append(kept, d)
+			report.addStyle(d)
+		}
+	}
+	return classes, strings.Join(kept, "; ")
+}
+
+func getAttr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func setAttr(n *html.Node, key, val string) {
+	for i := range n.Attr {
+		if n.Attr[i].Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
+
+func removeAttr(n *html.Node, key string) {
+	for i := range n.Attr {
+		if n.Attr[i].Key == key {
+			n.Attr = append(n.Attr[:i], n.Attr[i+1:]...)
+			return
+		}
+	}
+}
+
+func addClasses(n *html.Node, classes ...string) {
+	existing, _ := getAttr(n, "class")
+	have := strings.Fields(existing)
+	for _, c := range classes {
+		found := false
+		for _, v := range have {
+			if v == c {
+				found = true
+				break
+			}
+		}
+		if !found && c != "" {
+			have = append(have, c)
+		}
+	}
+	if len(have) > 0 {
+		setAttr(n, "class", strings.Join(have, " "))
+	}
+}
+
+// unwrapNode replaces n with its children.
+func unwrapNode(n *html.Node) {
+	for n.FirstChild != nil {
+		ch := n.FirstChild
+		n.RemoveChild(ch)
+		n.Parent.InsertBefore(ch, n)
+	}
+	n.Parent.RemoveChild(n)
+}
+
+func hasBlockChild(n *html.Node) bool {
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		if ch.Type == html.ElementNode && blockElements[ch.Data] {
+			return true
+		}
+	}
+	return false
+}
+
+// renameToContainer turns a deprecated element into a span, or a div if it holds block content.
+func renameToContainer(n *html.Node) {
+	if n.Data == "center" || hasBlockChild(n) {
+		n.Data = "div"
+		n.DataAtom = atom.Div
+	} else {
+		n.Data = "span"
+		n.DataAtom = atom.Span
+	}
+}
+
+// isStrongOnlyParagraph matches <p><strong>Chapter 1</strong></p>, which is used as a subheading.
+func isStrongOnlyParagraph(n *html.Node) bool {
+	if n.Data != "p" {
+		return false
+	}
+	var strong *html.Node
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		switch ch.Type {
+		case html.TextNode:
+			if strings.TrimSpace(ch.Data) != "" {
+				return false
+			}
+		case html.ElementNode:
+			if strong != nil || (ch.Data != "strong" && ch.Data != "b") {
+				return false
+			}
+			strong = ch
+		}
+	}
+	return strong != nil
+}
+
+func modernizeNode(n *html.Node, report *LegacyReport) {
+	// Collect first, the loop body may move or remove the child
+	var children []*html.Node
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		children = append(children, ch)
+	}
+	for _, ch := range children {
+		modernizeNode(ch, report)
+	}
+	if n.Type != html.ElementNode {
+		return
+	}
+
+	var classes []string
+	if style, ok := getAttr(n, "style"); ok {
+		var rest string
+		classes, rest = styleToClasses(style, report)
+		if rest == "" {
+			removeAttr(n, "style")
+		} else {
+			setAttr(n, "style", rest)
+		}
+	}
+	if align, ok := getAttr(n, "align"); ok {
+		align = strings.ToLower(strings.TrimSpace(align))
+		if n.Data == "img" {
+			if c, ok := floatClasses[align]; ok {
+				classes = append(classes, c)
+			}
+		} else if c, ok := alignClasses[align]; ok {
+			classes = append(classes, c)
+		} else {
+			report.addMarkup(fmt.Sprintf("<%s align=%q>", n.Data, align))
+		}
+		removeAttr(n, "align")
+	}
+	for _, attr := range []string{"bgcolor", "background"} {
+		// colors are left to the reader, see story.css
+		removeAttr(n, attr)
+	}
+
+	switch n.Data {
+	case "center":
+		classes = append(classes, "center")
+		renameToContainer(n)
+	case "font":
+		if size, ok := getAttr(n, "size"); ok {
+			size = strings.TrimSpace(size)
+			num, err := strconv.Atoi(strings.TrimPrefix(size, "+"))
+			if err == nil && (strings.HasPrefix(size, "+") || strings.HasPrefix(size, "-")) {
+				num += 3
+			}
+			if err != nil {
+				report.addMarkup(fmt.Sprintf("<font size=%q>", size))
+			} else {
+				classes = append(classes, fontSizeClasses[num])
+			}
+		}
+		if face, ok := getAttr(n, "face"); ok {
+			if c, ok := fontFamilyClass(face); ok {
+				classes = append(classes, c)
+			} else {
+				report.addMarkup(fmt.Sprintf("<font face=%q>", face))
+			}
+		}
+		for _, attr := range []string{"size", "face", "color"} {
+			removeAttr(n, attr)
+		}
+		renameToContainer(n)
+	case "basefont":
+		n.Parent.RemoveChild(n)
+		return
+	default:
+		if c, ok := deprecatedInlineClasses[n.Data]; ok {
+			classes = append(classes, c)
+			renameToContainer(n)
+		} else if deprecatedUnwrap[n.Data] {
+			unwrapNode(n)
+			return
+		}
+	}
+	if isStrongOnlyParagraph(n) {
+		classes = append(classes, "subhead")
+	}
+
+	addClasses(n, classes...)
+	if n.Data == "span" && len(n.Attr) == 0 {
+		// Nothing left of a <font color>
+		unwrapNode(n)
+	}
+}
+
+// modernizeLegacyMarkup is the general cleanup pass of FixForEbook for presentational markup.
+func modernizeLegacyMarkup(p *client.WhateleyPage) *LegacyReport {
+	report := &LegacyReport{
+		StoryID: p.StoryID,
+		Styles:  make(map[string]int),
+		Markup:  make(map[string]int),
+	}
+	for _, n := range p.StoryBodySelection().Nodes {
+		modernizeNode(n, report)
+	}
+	return report
+}
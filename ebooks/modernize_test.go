@@ -0,0 +1,92 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package ebooks
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// modernizeFragment runs the legacy markup pass over an HTML fragment.
+func modernizeFragment(t *testing.T, in string) (string, *LegacyReport) {
+	doc, err := html.Parse(strings.NewReader("<html><head></head><body>" + in + "</body></html>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := doc.FirstChild.LastChild
+	report := &LegacyReport{StoryID: "1", Styles: make(map[string]int), Markup: make(map[string]int)}
+	modernizeNode(body, report)
+
+	var buf bytes.Buffer
+	for ch := body.FirstChild; ch != nil; ch = ch.NextSibling {
+		html.Render(&buf, ch)
+	}
+	return buf.String(), report
+}
+
+func TestModernizeLegacyMarkup(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     string
+		out    string
+		report string
+	}{
+		{"center", `<center>Title</center>`, `<div class="center">Title</div>`, ""},
+		{"font size and face", `<font size="5" face="Courier New">x</font>`, `<span class="font-x-large monospace">x</span>`, ""},
+		{"relative font size", `<font size="-1">x</font>`, `<span class="font-small">x</span>`, ""},
+		{"font color only", `a<font color="red">b</font>c`, `abc`, ""},
+		{"font around blocks", `<font size="2"><p>a</p><p>b</p></font>`, `<div class="font-small"><p>a</p><p>b</p></div>`, ""},
+		{"unknown font face", `<font face="Papyrus">x</font>`, `x`, `unmapped markup <font face="Papyrus">`},
+		{"align", `<p align="right">x</p>`, `<p class="align-right">x</p>`, ""},
+		{"unknown align", `<p align="top">x</p>`, `<p>x</p>`, `unmapped markup <p align="top">`},
+		{"image align", `<img src="a.png" align="left">`, `<img src="a.png" class="float-left"/>`, ""},
+		{"pt font size", `<span style="font-size: 16pt">x</span>`, `<span class="font-large">x</span>`, ""},
+		{"normal font size", `<span style="font-size: 12pt">x</span>`, `x`, ""},
+		{"style classes", `<p style="text-align: center; font-weight: bold; font-style: italic">x</p>`, `<p class="center bold italic">x</p>`, ""},
+		{"word styles", `<p style="mso-margin-top-alt: auto; color: #000">x</p>`, `<p>x</p>`, ""},
+		{"unmapped style pt to em", `<p style="margin-left: 18pt; text-align: center">x</p>`, `<p style="margin-left: 1.5em" class="center">x</p>`,
+			`unmapped style "margin-left: 1.5em"`},
+		{"existing class", `<p class="x" align="center">y</p>`, `<p class="x center">y</p>`, ""},
+		{"deprecated inline", `<u>a</u><strike>b</strike><tt>c</tt>`, `<span class="underline">a</span><span class="strike">b</span><span class="monospace">c</span>`, ""},
+		{"unwrapped", `<blink>a</blink><nobr>b</nobr>`, `ab`, ""},
+		{"basefont", `<basefont size="3">a`, `a`, ""},
+		{"strong-only paragraph", `<p> <strong>Chapter 1</strong> </p>`, `<p class="subhead"> <strong>Chapter 1</strong> </p>`, ""},
+		{"not strong-only", `<p><strong>Bold</strong> text</p>`, `<p><strong>Bold</strong> text</p>`, ""},
+		{"bgcolor", `<table bgcolor="#fff"><tbody><tr><td>x</td></tr></tbody></table>`, `<table><tbody><tr><td>x</td></tr></tbody></table>`, ""},
+	}
+	for _, tt := range tests {
+		out, report := modernizeFragment(t, tt.in)
+		if out != tt.out {
+			t.Errorf("%s:\n  in:  %s\n  got: %s\n  exp: %s", tt.name, tt.in, out, tt.out)
+		}
+		var buf bytes.Buffer
+		report.WriteTo(&buf)
+		if tt.report == "" && buf.Len() != 0 {
+			t.Errorf("%s: unexpected report:\n%s", tt.name, buf.String())
+		} else if tt.report != "" && !strings.Contains(buf.String(), "#1: "+tt.report) {
+			t.Errorf("%s: report does not contain %s:\n%s", tt.name, tt.report, buf.String())
+		}
+	}
+}
+
+func TestPtToEm(t *testing.T) {
+	tests := []struct {
+		in, out string
+	}{
+		{"18pt", "1.5em"},
+		{"margin: 6pt 0 10pt", "margin: 0.5em 0 0.833em"},
+		{"-3pt", "-0.25em"},
+		{".5pt", "0.042em"},
+		{"12px", "12px"},
+		{"12ptx", "12ptx"},
+	}
+	for _, tt := range tests {
+		if out := ptToEm(tt.in); out != tt.out {
+			t.Errorf("ptToEm(%q) = %q, expected %q", tt.in, out, tt.out)
+		}
+	}
+}
@@ -0,0 +1,379 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package ebooks
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+
+	"github.com/riking/whateley-ebooks/client"
+)
+
+// TextFormat selects the output of the text renderers.
+type TextFormat int
+
+const (
+	// PlainText has one paragraph per line, with _italics_ and *bold* marked.
+	PlainText TextFormat = iota
+	// Markdown is CommonMark.
+	Markdown
+)
+
+// textBlockElements start a new paragraph in the text output.
+var textBlockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "center": true, "dd": true,
+	"div": true, "dl": true, "dt": true, "figure": true, "footer": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hr": true, "li": true, "ol": true,
+	"p": true, "pre": true, "section": true, "table": true, "tbody": true, "td": true, "th": true,
+	"thead": true, "tr": true, "ul": true,
+}
+
+var textSpaceRegexp = regexp.MustCompile(`[ \t\r\n\x{00a0}]+`)
+
+// Characters that have a meaning in Markdown inline text
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`, `<`, `\<`,
+)
+
+// Characters that would end a Markdown link destination early
+var markdownURLEscaper = strings.NewReplacer(
+	" ", "%20", "(", "%28", ")", "%29", "<", "%3C", ">", "%3E",
+)
+
+// Lines that would start a block in Markdown: headings, lists, quotes, rules, setext underlines
+// and code fences
+var markdownBlockStart = regexp.MustCompile(`\A(?:#{1,6}(?:\s|\z)|[-+*](?:\s|\z)|>|=+\s*\z|(?:-\s*){3,}\z|~~~)`)
+var markdownOrderedListStart = regexp.MustCompile(`\A(\d{1,9})([.)])(\s|\z)`)
+
+// escapeMarkdownLine escapes a marker at the start of a line of text, after the inline escapes.
+func escapeMarkdownLine(line string) string {
+	if m := markdownOrderedListStart.FindStringSubmatchIndex(line); m != nil {
+		// 1. -> 1\.
+		return line[:m[4]] + `\` + line[m[4]:]
+	}
+	if markdownBlockStart.MatchString(line) {
+		return `\` + line
+	}
+	return line
+}
+
+type textRenderer struct {
+	format TextFormat
+	out    []string
+}
+
+func (t *textRenderer) md() bool {
+	return t.format == Markdown
+}
+
+// paragraph adds a block of text. Line breaks from <br> are kept, and every line gets the prefix.
+// In Markdown, text that would start a heading, list, quote or rule is escaped.
+func (t *textRenderer) paragraph(prefix, text string) {
+	t.lines(prefix, text, t.md())
+}
+
+// markup adds a paragraph of generated markup, e.g. a heading, without escaping.
+func (t *textRenderer) markup(prefix, text string) {
+	t.lines(prefix, text, false)
+}
+
+func (t *textRenderer) lines(prefix, text string, escape bool) {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(textSpaceRegexp.ReplaceAllString(line, " "))
+		if escape {
+			line = escapeMarkdownLine(line)
+		}
+		if line != "" {
+			lines = append(lines, prefix+line)
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+	sep := "\n"
+	if t.md() {
+		// hard line break
+		sep = "  \n"
+	}
+	t.out = append(t.out, strings.Join(lines, sep))
+}
+
+// quotePrefix is put in front of the lines of blockquotes, .lyrics, and .dream.
+func (t *textRenderer) quotePrefix() string {
+	if t.md() {
+		return "> "
+	}
+	return "    "
+}
+
+func isQuoteBlock(n *html.Node) bool {
+	if n.Data == "blockquote" {
+		return true
+	}
+	for _, class := range strings.Fields(attrValue(n, "class")) {
+		if class == "lyrics" || class == "dream" {
+			return true
+		}
+	}
+	return false
+}
+
+// renderBlock renders the children of n. Runs of inline content become paragraphs.
+func (t *textRenderer) renderBlock(n *html.Node, prefix string) {
+	var inline bytes.Buffer
+	flush := func() {
+		t.paragraph(prefix, inline.String())
+		inline.Reset()
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && textBlockElements[c.Data] {
+			flush()
+			t.block(c, prefix)
+		} else {
+			t.inline(&inline, c)
+		}
+	}
+	flush()
+}
+
+func (t *textRenderer) block(n *html.Node, prefix string) {
+	switch {
+	case n.Data == "hr":
+		// scene break
+		t.markup(prefix, "* * *")
+	case isQuoteBlock(n):
+		t.renderBlock(n, prefix+t.quotePrefix())
+	case n.Data == "ul" || n.Data == "ol":
+		var items []string
+		num := 0
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode || c.Data != "li" {
+				continue
+			}
+			num++
+			var buf bytes.Buffer
+			t.inline(&buf, c)
+			text := strings.TrimSpace(textSpaceRegexp.ReplaceAllString(buf.String(), " "))
+			if n.Data == "ol" {
+				items = append(items, fmt.Sprintf("%s%d. %s", prefix, num, text))
+			} else {
+				items = append(items, fmt.Sprintf("%s- %s", prefix, text))
+			}
+		}
+		if len(items) > 0 {
+			t.out = append(t.out, strings.Join(items, "\n"))
+		}
+	case len(n.Data) == 2 && n.Data[0] == 'h' && n.Data[1] >= '1' && n.Data[1] <= '6':
+		var buf bytes.Buffer
+		t.inline(&buf, n)
+		text := strings.TrimSpace(textSpaceRegexp.ReplaceAllString(buf.String(), " "))
+		if t.md() && text != "" {
+			text = strings.Repeat("#", int(n.Data[1]-'0')) + " " + text
+		}
+		t.markup(prefix, text)
+	default:
+		t.renderBlock(n, prefix)
+	}
+}
+
+// wrapInline renders the children of n between the markers. Spaces at the ends stay outside the markers.
+func (t *textRenderer) wrapInline(buf *bytes.Buffer, n *html.Node, marker string) {
+	var inner bytes.Buffer
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		t.inline(&inner, c)
+	}
+	s := inner.String()
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		buf.WriteString(s)
+		return
+	}
+	if strings.TrimLeft(s, " \n") != s {
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(marker)
+	buf.WriteString(trimmed)
+	buf.WriteString(marker)
+	if strings.TrimRight(s, " \n") != s {
+		buf.WriteByte(' ')
+	}
+}
+
+func attrValue(n *html.Node, key string) string {
+	v, _ := getAttr(n, key)
+	return v
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, v := range strings.Fields(attrValue(n, "class")) {
+		if v == class {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *textRenderer) inline(buf *bytes.Buffer, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		text := textSpaceRegexp.ReplaceAllString(n.Data, " ")
+		if t.md() {
+			text = markdownEscaper.Replace(text)
+		}
+		buf.WriteString(text)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	italic, bold := "_", "*"
+	if t.md() {
+		italic, bold = "*", "**"
+	}
+	switch {
+	case n.Data == "script" || n.Data == "style":
+	case n.Data == "br":
+		buf.WriteByte('\n')
+	case n.Data == "em" || n.Data == "i" || n.Data == "cite" || hasClass(n, "italic"):
+		t.wrapInline(buf, n, italic)
+	case n.Data == "strong" || n.Data == "b" || hasClass(n, "bold"):
+		t.wrapInline(buf, n, bold)
+	case n.Data == "img":
+		alt := strings.TrimSpace(attrValue(n, "alt"))
+		src := attrValue(n, "src")
+		if t.md() && strings.Contains(src, "://") {
+			fmt.Fprintf(buf, "![%s](%s)", markdownEscaper.Replace(alt), markdownURLEscaper.Replace(strings.TrimSpace(src)))
+		} else if alt != "" {
+			// images bundled into an ebook have no URL to point to
+			fmt.Fprintf(buf, "[Image: %s]", alt)
+		}
+	case n.Data == "a" && t.md() && attrValue(n, "href") != "" && !strings.HasPrefix(attrValue(n, "href"), "#"):
+		var inner bytes.Buffer
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			t.inline(&inner, c)
+		}
+		fmt.Fprintf(buf, "[%s](%s)", strings.TrimSpace(inner.String()), markdownURLEscaper.Replace(strings.TrimSpace(attrValue(n, "href"))))
+	default:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && textBlockElements[c.Data] {
+				// block inside inline content, e.g. <li><p>
+				buf.WriteByte('\n')
+				t.inline(buf, c)
+				buf.WriteByte('\n')
+			} else {
+				t.inline(buf, c)
+			}
+		}
+	}
+}
+
+// heading adds a title line, a markdown heading of the given level or plain text.
+func (t *textRenderer) heading(level int, text string) {
+	if t.md() {
+		t.out = append(t.out, strings.Repeat("#", level)+" "+markdownEscaper.Replace(text))
+	} else {
+		t.out = append(t.out, text)
+	}
+}
+
+func (t *textRenderer) byline(author string) {
+	if t.md() {
+		t.out = append(t.out, "*by "+markdownEscaper.Replace(author)+"*")
+	} else {
+		t.out = append(t.out, "by "+author)
+	}
+}
+
+func (t *textRenderer) story(page *client.WhateleyPage, level int) {
+	t.heading(level, page.Title())
+	if page.Authors() != "" {
+		t.byline(page.Authors())
+	}
+	for _, n := range page.StoryBodySelection().Nodes {
+		t.renderBlock(n, "")
+	}
+}
+
+func (t *textRenderer) writeTo(w io.Writer) error {
+	_, err := io.WriteString(w, strings.Join(t.out, "\n\n")+"\n")
+	return err
+}
+
+// RenderStoryText writes a processed story (see FixForEbook) as plain text or Markdown.
+// Scene breaks become "* * *", and blockquotes, .lyrics and .dream are indented or quoted.
+func RenderStoryText(w io.Writer, page *client.WhateleyPage, format TextFormat) error {
+	t := textRenderer{format: format}
+	t.story(page, 1)
+	return t.writeTo(w)
+}
+
+// CreateText writes all stories of the book into one plain text or Markdown file.
+func CreateText(ed *EpubDefinition, access *client.WANetwork, filename string, format TextFormat) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return errors.Wrap(err, "could not create output file")
+	}
+	defer file.Close()
+
+	ed.PrepareAssets()
+
+	ed.lock.Lock()
+	defer ed.lock.Unlock()
+
+	ed.textOnly = true
+	err = ed.prepareAll(access)
+	if err != nil {
+		return err
+	}
+
+	t := textRenderer{format: format}
+	t.heading(1, ed.Title)
+	t.byline(ed.Author)
+
+	wordCount := 0
+	for i := range ed.Parts {
+		v := &ed.Parts[i]
+		if v.IsCoverPage() && !v.fixCvr {
+			doc, err := html.Parse(strings.NewReader(v.CoverPage))
+			if err != nil {
+				return errors.Wrap(err, "parsing coverpage")
+			}
+			if format == PlainText {
+				t.out = append(t.out, strings.Repeat("=", 40))
+			}
+			t.renderBlock(findBody(doc), "")
+		} else if v.IsContentPage() {
+			page, err := v.preparePage(access, ed)
+			if err != nil {
+				return errors.Wrapf(err, "preparing content for story #%s", v.Story.ID)
+			}
+			wordCount += page.WordCount()
+			if format == PlainText {
+				t.out = append(t.out, strings.Repeat("-", 40))
+			}
+			t.story(page, 2)
+		}
+	}
+
+	w := bufio.NewWriter(file)
+	err = t.writeTo(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		return errors.Wrapf(err, "writing %s", filename)
+	}
+	fmt.Printf("Created %s.\nWord Count: %d\n", filename, wordCount)
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package ebooks
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+
+	"github.com/riking/whateley-ebooks/client"
+	"github.com/riking/whateley-ebooks/client/fakesite"
+)
+
+func renderTextFragment(t *testing.T, in string, format TextFormat) string {
+	doc, err := html.Parse(strings.NewReader("<body>" + in + "</body>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := textRenderer{format: format}
+	r.renderBlock(findBody(doc), "")
+	return strings.Join(r.out, "\n\n")
+}
+
+func TestRenderText(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		plain    string
+		markdown string
+	}{
+		{"emphasis", `<p>An <em>odd</em> <strong>day </strong>.</p>`, `An _odd_ *day* .`, `An *odd* **day** .`},
+		{"inline escapes", `<p>a_b *c* [d] &lt;e&gt;</p>`, `a_b *c* [d] <e>`, `a\_b \*c\* \[d\] \<e>`},
+		{"block markers", `<p># not a heading</p><p>- not a list</p><p>1. not a list</p><p>&gt; not a quote</p>`,
+			"# not a heading\n\n- not a list\n\n1. not a list\n\n> not a quote",
+			"\\# not a heading\n\n\\- not a list\n\n1\\. not a list\n\n\\> not a quote"},
+		{"line breaks", `<p>one<br>- two</p>`, "one\n- two", "one  \n\\- two"},
+		{"scene break", `<p>a</p><hr><p>b</p>`, "a\n\n* * *\n\nb", "a\n\n* * *\n\nb"},
+		{"heading", `<h3>Part <em>1</em></h3>`, "Part _1_", "### Part *1*"},
+		{"blockquote", `<blockquote><p>quoted</p></blockquote>`, "    quoted", "> quoted"},
+		{"list", `<ol><li>one</li><li>two</li></ol>`, "1. one\n2. two", "1. one\n2. two"},
+		{"link", `<p><a href="http://example.com/a b(c).html">link</a></p>`, "link", "[link](http://example.com/a%20b%28c%29.html)"},
+		{"fragment link", `<p><a href="#n1">1</a></p>`, "1", "1"},
+		{"remote image", `<p><img src="http://example.com/x (1).png" alt="An image"></p>`, "[Image: An image]", "![An image](http://example.com/x%20%281%29.png)"},
+		{"bundled image", `<p><img src="../Images/x.png" alt="An image"></p>`, "[Image: An image]", "[Image: An image]"},
+	}
+	for _, tt := range tests {
+		if out := renderTextFragment(t, tt.in, PlainText); out != tt.plain {
+			t.Errorf("%s (text):\n  got: %q\n  exp: %q", tt.name, out, tt.plain)
+		}
+		if out := renderTextFragment(t, tt.in, Markdown); out != tt.markdown {
+			t.Errorf("%s (markdown):\n  got: %q\n  exp: %q", tt.name, out, tt.markdown)
+		}
+	}
+}
+
+// Text output does not download images, and Markdown links to them on the site.
+func TestCreateTextImages(t *testing.T) {
+	site := fakesite.Demo()
+	srv := httptest.NewServer(site)
+	defer srv.Close()
+	transport, err := fakesite.Redirect(srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	access := client.New(client.Options{Transport: transport, MaxRetries: -1})
+
+	dir, err := ioutil.TempDir("", "plaintext")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, format := range []TextFormat{PlainText, Markdown} {
+		ed := &EpubDefinition{Title: "Test", Author: "Demo Author"}
+		part := TOCEntry{TOC: "First Day"}
+		part.Story.ID = "1"
+		ed.Parts = []TOCEntry{part}
+
+		filename := filepath.Join(dir, "test.txt")
+		err = CreateText(ed, access, filename, format)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		b, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "[Image: demo]"
+		if format == Markdown {
+			want = "![demo](http://whateleyacademy.net/images/stories/demo.png)"
+		}
+		if !strings.Contains(string(b), want) {
+			t.Errorf("format %d: output does not contain %s:\n%s", format, want, b)
+		}
+	}
+	if n := site.Requests("/images/stories/demo.png"); n != 0 {
+		t.Errorf("the image was downloaded %d times", n)
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package ebooks
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// A Profile adjusts the output for one kind of reading device.
+type Profile struct {
+	Name string
+	// Images larger than this are scaled down. Zero means no limit.
+	MaxImageWidth  int
+	MaxImageHeight int
+	// ExtraCSS is appended to story.css.
+	ExtraCSS string
+	// MaxXHTMLSize is the size in bytes of the largest text file the device handles well.
+	// Zero means no limit.
+	MaxXHTMLSize int
+}
+
+// story.css makes every image full width, which blows up small images on large screens
+const profileImageCSS = `
+img {
+    width: auto !important;
+    max-width: 100% !important;
+    height: auto !important;
+}
+`
+
+var profiles = map[string]*Profile{
+	"kindle-paperwhite": {
+		Name:           "kindle-paperwhite",
+		MaxImageWidth:  1072,
+		MaxImageHeight: 1448,
+		ExtraCSS:       profileImageCSS,
+		MaxXHTMLSize:   250 * 1024,
+	},
+	"kobo": {
+		Name:           "kobo",
+		MaxImageWidth:  1264,
+		MaxImageHeight: 1680,
+		ExtraCSS:       profileImageCSS,
+		MaxXHTMLSize:   250 * 1024,
+	},
+	"phone": {
+		Name:           "phone",
+		MaxImageWidth:  1080,
+		MaxImageHeight: 1920,
+		ExtraCSS: profileImageCSS + `
+body {
+    margin: 0 0.5em;
+}
+p {
+    text-indent: 1.5em;
+}
+`,
+		MaxXHTMLSize: 150 * 1024,
+	},
+	"desktop": {
+		Name: "desktop",
+		ExtraCSS: profileImageCSS + `
+body {
+    max-width: 40em;
+    line-height: 1.5;
+}
+`,
+	},
+}
+
+// GetProfile returns the named output profile.
+func GetProfile(name string) (*Profile, error) {
+	p, ok := profiles[name]
+	if !ok {
+		return nil, errors.Errorf("unknown profile %s, expected one of %v", name, ProfileNames())
+	}
+	return p, nil
+}
+
+// ProfileNames lists the known output profiles.
+func ProfileNames() []string {
+	var names []string
+	for k := range profiles {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetProfile selects the output profile for the book. A nil profile makes a generic book.
+func (ed *EpubDefinition) SetProfile(p *Profile) {
+	ed.profile = p
+}
+
+// styleSheet is story.css, or its replacement, with the book's stylesheets, fonts, and the profile's
+// additions. embedFonts puts the font files into the stylesheet, for the single-file HTML output.
+func (ed *EpubDefinition) styleSheet(embedFonts bool) ([]byte, error) {
+	b := ed.css
+	if b == nil {
+		var err error
+		b, err = Asset("story.css")
+		if err != nil {
+			return nil, errors.Wrap(err, "could not find embedded asset story.css")
+		}
+	}
+	if len(ed.extraCSS) > 0 {
+		b = append(append([]byte(nil), b...), ed.extraCSS...)
+	}
+	if fonts := ed.fontCSS(embedFonts); fonts != "" {
+		b = append(append([]byte(nil), b...), fonts...)
+	}
+	if ed.profile != nil && ed.profile.ExtraCSS != "" {
+		b = append(append([]byte(nil), b...), fmt.Sprintf("\n/* profile: %s */\n%s", ed.profile.Name, ed.profile.ExtraCSS)...)
+	}
+	return b, nil
+}
+
+// checkXHTMLSize warns about files that are still too large for the profile's device after
+// splitLongParts, because the story has no scene break or heading to split at.
+func (ed *EpubDefinition) checkXHTMLSize(filename string, size int) {
+	if ed.profile == nil || ed.profile.MaxXHTMLSize == 0 || size <= ed.profile.MaxXHTMLSize {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "[ebooks] warning: %s is %d bytes, over the %d byte limit of profile %s\n",
+		filename, size, ed.profile.MaxXHTMLSize, ed.profile.Name)
+}
+
+// scaleImage returns the image scaled down to fit the profile's limits.
+// Images that cannot be decoded, like SVG, and GIFs are returned unchanged.
+func (p *Profile) scaleImage(img *bundledImage) *bundledImage {
+	if p == nil || (p.MaxImageWidth == 0 && p.MaxImageHeight == 0) {
+		return img
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(img.body))
+	if err != nil || (format != "jpeg" && format != "png") {
+		return img
+	}
+	scale := 1.0
+	if p.MaxImageWidth != 0 && cfg.Width > p.MaxImageWidth {
+		scale = float64(p.MaxImageWidth) / float64(cfg.Width)
+	}
+	if p.MaxImageHeight != 0 && float64(cfg.Height)*scale > float64(p.MaxImageHeight) {
+		scale = float64(p.MaxImageHeight) / float64(cfg.Height)
+	}
+	if scale >= 1 {
+		return img
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(img.body))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ebooks] warning: could not scale image %s: %s\n", img.Target, err)
+		return img
+	}
+	width := int(float64(cfg.Width)*scale + 0.5)
+	height := int(float64(cfg.Height)*scale + 0.5)
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+	dst := downscale(src, width, height)
+
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 90})
+	} else {
+		err = png.Encode(&buf, dst)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ebooks] warning: could not scale image %s: %s\n", img.Target, err)
+		return img
+	}
+	scaled := *img
+	scaled.body = buf.Bytes()
+	return &scaled
+}
+
+// downscale shrinks the image by averaging the source pixels that cover each target pixel.
+func downscale(src image.Image, width, height int) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := b.Min.Y + y*b.Dy()/height
+		y1 := b.Min.Y + (y+1)*b.Dy()/height
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0 := b.Min.X + x*b.Dx()/width
+			x1 := b.Min.X + (x+1)*b.Dx()/width
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					bl += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(bl / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,276 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package ebooks
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Long stories are split into several files, so that page turns stay fast on e-ink readers.
+// The story is only cut at scene breaks and headings, and the first file stays the TOC target.
+
+// splitLimits returns the largest file size in bytes and the most words a story file should have.
+// Zero means no limit. Without a split-size, the size limit is the profile's, if any; a split-size
+// of -1 turns splitting by size off, and files over the profile's limit are only warned about (see
+// checkXHTMLSize).
+func (ed *EpubDefinition) splitLimits() (size, words int) {
+	size = ed.SplitSize
+	if size == 0 && ed.profile != nil {
+		size = ed.profile.MaxXHTMLSize
+	} else if size > 0 && ed.profile != nil && ed.profile.MaxXHTMLSize != 0 && ed.profile.MaxXHTMLSize < size {
+		size = ed.profile.MaxXHTMLSize
+	}
+	if size < 0 {
+		size = 0
+	}
+	words = ed.SplitWords
+	if words < 0 {
+		words = 0
+	}
+	return size, words
+}
+
+type countingWriter int
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	*c += countingWriter(len(p))
+	return len(p), nil
+}
+
+func xhtmlSize(n *html.Node) int {
+	var c countingWriter
+	RenderXHTML(&c, n)
+	return int(c)
+}
+
+func nodeText(n *html.Node, w io.Writer) {
+	if n.Type == html.TextNode {
+		io.WriteString(w, n.Data)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		nodeText(c, w)
+	}
+}
+
+func nodeWords(n *html.Node) int {
+	var b strings.Builder
+	nodeText(n, &b)
+	return len(strings.Fields(b.String()))
+}
+
+// isSplitPoint reports whether a story file can start with n: a scene break or a heading.
+func isSplitPoint(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	switch n.DataAtom {
+	case atom.Hr, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		return true
+	case atom.P:
+		// "Chapter N" paragraphs, see modernizeLegacyMarkup
+		return hasClass(n, "subhead")
+	}
+	return false
+}
+
+func cloneNode(n *html.Node) *html.Node {
+	c := &html.Node{
+		Type:      n.Type,
+		DataAtom:  n.DataAtom,
+		Data:      n.Data,
+		Namespace: n.Namespace,
+		Attr:      append([]html.Attribute(nil), n.Attr...),
+	}
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		c.AppendChild(cloneNode(ch))
+	}
+	return c
+}
+
+// splitPieces groups the body children into pieces under the limits. A new piece only starts at a
+// split point.
+func splitPieces(body *html.Node, maxSize, maxWords int) [][]*html.Node {
+	// segments start at split points
+	var segments [][]*html.Node
+	for c := body.FirstChild; c != nil; c = c.NextSibling {
+		if len(segments) == 0 || isSplitPoint(c) {
+			segments = append(segments, nil)
+		}
+		segments[len(segments)-1] = append(segments[len(segments)-1], c)
+	}
+
+	var pieces [][]*html.Node
+	var size, words int
+	for _, seg := range segments {
+		segSize, segWords := 0, 0
+		for _, n := range seg {
+			segSize += xhtmlSize(n)
+			segWords += nodeWords(n)
+		}
+		over := (maxSize != 0 && size+segSize > maxSize) || (maxWords != 0 && words+segWords > maxWords)
+		if len(pieces) == 0 || (over && size > 0) {
+			pieces = append(pieces, nil)
+			size, words = 0, 0
+		}
+		pieces[len(pieces)-1] = append(pieces[len(pieces)-1], seg...)
+		size += segSize
+		words += segWords
+	}
+	return pieces
+}
+
+func collectIDs(n *html.Node, filename string, ids map[string]string) {
+	if n.Type == html.ElementNode {
+		if id := attrValue(n, "id"); id != "" {
+			ids[id] = filename
+		}
+		if n.DataAtom == atom.A {
+			if name := attrValue(n, "name"); name != "" {
+				ids[name] = filename
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		collectIDs(c, filename, ids)
+	}
+}
+
+// fixFragmentLinks points #fragment links at the piece that now has the target.
+func fixFragmentLinks(n *html.Node, filename string, ids map[string]string) {
+	if n.Type == html.ElementNode && n.DataAtom == atom.A {
+		for i, a := range n.Attr {
+			if a.Key != "href" || !strings.HasPrefix(a.Val, "#") {
+				continue
+			}
+			if target, ok := ids[a.Val[1:]]; ok && target != filename {
+				n.Attr[i].Val = path.Base(target) + a.Val
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		fixFragmentLinks(c, filename, ids)
+	}
+}
+
+// splitPart splits a rendered story. The pieces after the first are named {id}-2.html, {id}-3.html, ...
+// ids maps every element id in the story to the file that has it.
+func (ed *EpubDefinition) splitPart(p textPart, maxSize, maxWords int, ids map[string]string) []textPart {
+	body := findBody(p.doc)
+	if body == nil {
+		return []textPart{p}
+	}
+	pieces := splitPieces(body, maxSize, maxWords)
+	if len(pieces) <= 1 {
+		collectIDs(p.doc, p.Filename, ids)
+		return []textPart{p}
+	}
+
+	// detach the content, then give each piece its own copy of the page
+	for body.FirstChild != nil {
+		body.RemoveChild(body.FirstChild)
+	}
+	blank := cloneNode(p.doc)
+	base := strings.TrimSuffix(p.Id, ".html")
+	result := make([]textPart, len(pieces))
+	for i, piece := range pieces {
+		part := p
+		if i > 0 {
+			id := fmt.Sprintf("%s-%d.html", base, i+1)
+			part.contentEntry = contentEntry{
+				Filename:    path.Join(path.Dir(p.Filename), id),
+				Id:          id,
+				ContentType: p.ContentType,
+				TOCNest:     p.TOCNest,
+				StoryID:     p.StoryID,
+			}
+			part.doc = cloneNode(blank)
+		}
+		pieceBody := findBody(part.doc)
+		for _, n := range piece {
+			pieceBody.AppendChild(n)
+		}
+		collectIDs(pieceBody, part.Filename, ids)
+		result[i] = part
+	}
+
+	localIDs := make(map[string]string)
+	for _, v := range result {
+		collectIDs(v.doc, v.Filename, localIDs)
+	}
+	for _, v := range result {
+		fixFragmentLinks(v.doc, v.Filename, localIDs)
+	}
+	return result
+}
+
+// splitLongParts splits every story over the size or word limit, and points anchor entries at the
+// piece that has the anchor.
+func (ed *EpubDefinition) splitLongParts(parts []textPart) []textPart {
+	maxSize, maxWords := ed.splitLimits()
+	if maxSize == 0 && maxWords == 0 {
+		return parts
+	}
+
+	// "Text/34-whisper-one.html#epilog" -> file
+	anchors := make(map[string]string)
+	var result []textPart
+	for _, v := range parts {
+		if v.doc == nil || v.StoryID == "" {
+			result = append(result, v)
+			continue
+		}
+		ids := make(map[string]string)
+		pieces := ed.splitPart(v, maxSize, maxWords, ids)
+		if len(pieces) > 1 {
+			fmt.Printf("#%s: split into %d files\n", v.StoryID, len(pieces))
+		}
+		for id, file := range ids {
+			anchors[v.Filename+"#"+id] = file
+		}
+		result = append(result, pieces...)
+	}
+
+	for i, v := range result {
+		if v.doc != nil {
+			fixStoryLinks(v.doc, v.Filename, anchors)
+			continue
+		}
+		if file, ok := anchors[v.Filename]; ok {
+			result[i].Filename = file + v.Filename[strings.IndexByte(v.Filename, '#'):]
+		}
+	}
+	return result
+}
+
+// fixStoryLinks points "story.html#fragment" links from other stories, see rewriteStoryLinks, at
+// the piece that has the target.
+func fixStoryLinks(n *html.Node, filename string, anchors map[string]string) {
+	if n.Type == html.ElementNode && n.DataAtom == atom.A {
+		for i, a := range n.Attr {
+			if a.Key != "href" || strings.HasPrefix(a.Val, "#") || strings.Contains(a.Val, ":") {
+				continue
+			}
+			idx := strings.IndexByte(a.Val, '#')
+			if idx == -1 {
+				continue
+			}
+			if target, ok := anchors[path.Join(path.Dir(filename), a.Val)]; ok {
+				if target == filename {
+					n.Attr[i].Val = a.Val[idx:]
+				} else {
+					n.Attr[i].Val = path.Base(target) + a.Val[idx:]
+				}
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		fixStoryLinks(c, filename, anchors)
+	}
+}
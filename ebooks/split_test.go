@@ -0,0 +1,121 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package ebooks
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestSplitLimits(t *testing.T) {
+	kobo := &Profile{Name: "kobo", MaxXHTMLSize: 1000}
+	tests := []struct {
+		name      string
+		splitSize int
+		words     int
+		profile   *Profile
+		size      int
+		maxWords  int
+	}{
+		{"off by default", 0, 0, nil, 0, 0},
+		{"split-size", 2000, 0, nil, 2000, 0},
+		{"profile limit", 0, 0, kobo, 1000, 0},
+		{"profile limit is smaller", 2000, 0, kobo, 1000, 0},
+		{"split-size is smaller", 500, 0, kobo, 500, 0},
+		{"disabled", -1, 0, nil, 0, 0},
+		{"disabled with a profile", -1, 0, kobo, 0, 0},
+		{"profile without a limit", 0, 0, &Profile{Name: "none"}, 0, 0},
+		{"words", 0, 3000, nil, 0, 3000},
+		{"negative words", 0, -1, nil, 0, 0},
+	}
+	for _, tt := range tests {
+		ed := &EpubDefinition{SplitSize: tt.splitSize, SplitWords: tt.words, profile: tt.profile}
+		size, words := ed.splitLimits()
+		if size != tt.size || words != tt.maxWords {
+			t.Errorf("%s: got %d bytes, %d words; expected %d, %d", tt.name, size, words, tt.size, tt.maxWords)
+		}
+	}
+}
+
+func storyPart(t *testing.T, filename, body string) textPart {
+	doc, err := html.Parse(strings.NewReader("<html><head><title>x</title></head><body>" + body + "</body></html>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := strings.TrimPrefix(filename, "Text/")
+	return textPart{contentEntry{Filename: filename, Id: id, ContentType: "application/xhtml+xml", TOC: "Whisper", StoryID: "34"}, doc}
+}
+
+func bodyHTML(p textPart) string {
+	var buf bytes.Buffer
+	for c := findBody(p.doc).FirstChild; c != nil; c = c.NextSibling {
+		html.Render(&buf, c)
+	}
+	return buf.String()
+}
+
+// Stories are split at scene breaks and headings, and links and anchor entries follow the content.
+func TestSplitLongParts(t *testing.T) {
+	words := func(n int) string { return strings.Repeat("word ", n) }
+	story := `<p>` + words(10) + `<a href="#epilog">end</a></p>` +
+		`<p>` + words(10) + `</p>` +
+		`<hr/><p>` + words(10) + `</p>` +
+		`<h2>Chapter 2</h2><p>` + words(5) + `</p>` +
+		`<p class="subhead"><strong>Epilog</strong></p><p id="epilog">` + words(10) + `</p>`
+	parts := []textPart{
+		storyPart(t, "Text/34-whisper-one.html", story),
+		{contentEntry{Filename: "Text/34-whisper-one.html#epilog", TOC: "Epilog", TOCNest: 1}, nil},
+		storyPart(t, "Text/35-part-two.html", `<p><a href="34-whisper-one.html#epilog">back</a></p>`),
+	}
+	parts[2].StoryID, parts[2].TOC = "35", "Part Two"
+
+	ed := &EpubDefinition{SplitWords: 20}
+	result := ed.splitLongParts(parts)
+
+	var files []string
+	for _, v := range result {
+		files = append(files, v.Filename)
+	}
+	want := []string{
+		"Text/34-whisper-one.html", "Text/34-whisper-one-2.html", "Text/34-whisper-one-3.html",
+		"Text/34-whisper-one-3.html#epilog", "Text/35-part-two.html",
+	}
+	if strings.Join(files, " ") != strings.Join(want, " ") {
+		t.Fatalf("wrong files:\n  got: %v\n  exp: %v", files, want)
+	}
+
+	first, second, third := result[0], result[1], result[2]
+	if first.TOC != "Whisper" || second.TOC != "" || third.TOC != "" {
+		t.Errorf("only the first piece should be in the TOC: %q %q %q", first.TOC, second.TOC, third.TOC)
+	}
+	if second.Id != "34-whisper-one-2.html" || second.StoryID != "34" {
+		t.Errorf("wrong manifest entry for the second piece: %+v", second.contentEntry)
+	}
+	if b := bodyHTML(second); !strings.HasPrefix(b, "<hr/>") || !strings.Contains(b, "<h2>Chapter 2</h2>") || strings.Contains(b, "end</a>") {
+		t.Errorf("second piece is not the scene break and chapter 2: %s", b)
+	}
+	if b := bodyHTML(third); !strings.HasPrefix(b, `<p class="subhead">`) || !strings.Contains(b, `id="epilog"`) || strings.Contains(b, "<hr/>") {
+		t.Errorf("third piece is not the epilog: %s", b)
+	}
+	if !strings.Contains(bodyHTML(first), `<a href="34-whisper-one-3.html#epilog">`) {
+		t.Errorf("fragment link not pointed at the third piece: %s", bodyHTML(first))
+	}
+	if !strings.Contains(bodyHTML(result[4]), `<a href="34-whisper-one-3.html#epilog">`) {
+		t.Errorf("link from another story not pointed at the third piece: %s", bodyHTML(result[4]))
+	}
+	// every piece has the page head
+	if head := findBody(third.doc).Parent.FirstChild; head == nil || head.Data != "head" || head.FirstChild == nil {
+		t.Errorf("piece is not a full page")
+	}
+
+	// no limits, no split
+	ed = &EpubDefinition{SplitSize: -1}
+	parts = []textPart{storyPart(t, "Text/34-whisper-one.html", story)}
+	if result := ed.splitLongParts(parts); len(result) != 1 {
+		t.Errorf("split with splitting turned off: %d files", len(result))
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package ebooks
+
+import (
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// builtinTemplates are the embedded templates that can be replaced, by file name.
+// story.css can be replaced as well.
+var builtinTemplates = map[string]*template.Template{
+	"about.html":       aboutPageTmpl,
+	"attribution.html": attributionTmpl,
+	"cover.html":       coverPageTmpl,
+	"cover-image.html": coverImagePageTmpl,
+	"part.html":        storyPageTmpl,
+}
+
+const styleSheetName = "story.css"
+
+var templateDir string
+
+// SetTemplateDir sets a directory of replacements for the embedded templates and story.css, which
+// is checked before the embedded files. Files the directory does not have use the embedded version.
+func SetTemplateDir(dir string) {
+	templateDir = dir
+}
+
+func isReplaceable(name string) bool {
+	_, ok := builtinTemplates[name]
+	return ok || name == styleSheetName
+}
+
+func replaceableNames() []string {
+	names := []string{styleSheetName}
+	for k := range builtinTemplates {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// templateFile returns the file that replaces an embedded file, or "" if there is none.
+func (ed *EpubDefinition) templateFile(name, baseDir string) string {
+	if file, ok := ed.Templates[name]; ok {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(baseDir, file)
+		}
+		return file
+	}
+	if templateDir != "" {
+		file := filepath.Join(templateDir, name)
+		if _, err := os.Stat(file); err == nil {
+			return file
+		}
+	}
+	return ""
+}
+
+// LoadTemplates reads the replacement templates and extra stylesheets of the book, and the files in
+// the template directory. Relative paths in the definition are relative to baseDir, the directory of
+// the definition file. Call it after loading the definition, so that mistakes are found before
+// any story is downloaded.
+func (ed *EpubDefinition) LoadTemplates(baseDir string) error {
+	for name := range ed.Templates {
+		if !isReplaceable(name) {
+			return errors.Errorf("templates: %s is not a replaceable file, expected one of %v", name, replaceableNames())
+		}
+	}
+
+	ed.templates = make(map[string]*template.Template)
+	ed.css = nil
+	for name := range builtinTemplates {
+		file := ed.templateFile(name, baseDir)
+		if file == "" {
+			continue
+		}
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return errors.Wrapf(err, "could not read template %s", name)
+		}
+		tmpl, err := template.New(name).Parse(string(b))
+		if err != nil {
+			return errors.Wrapf(err, "invalid template %s", file)
+		}
+		ed.templates[name] = tmpl
+	}
+	if file := ed.templateFile(styleSheetName, baseDir); file != "" {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return errors.Wrapf(err, "could not read %s", styleSheetName)
+		}
+		ed.css = b
+	}
+
+	ed.extraCSS = nil
+	for _, file := range ed.Stylesheets {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(baseDir, file)
+		}
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return errors.Wrap(err, "could not read stylesheet")
+		}
+		ed.extraCSS = append(ed.extraCSS, fmt.Sprintf("\n/* %s */\n%s", filepath.Base(file), b)...)
+	}
+	return nil
+}
+
+// template returns the book's replacement for an embedded template, or the embedded one.
+func (ed *EpubDefinition) template(name string) *template.Template {
+	if tmpl, ok := ed.templates[name]; ok {
+		return tmpl
+	}
+	return builtinTemplates[name]
+}
@@ -0,0 +1,516 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+// Package validate performs structural checks on generated .epub files.
+//
+// It is not a replacement for epubcheck, but catches the mistakes this program is likely to make:
+// a bad zip layout, manifest entries without files, broken image and TOC links, and a malformed NCX.
+package validate // import "github.com/riking/whateley-ebooks/ebooks/validate"
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+)
+
+// A Problem is a single issue found in an epub file.
+type Problem struct {
+	// File is the path inside the zip that has the problem.
+	File string
+	// StoryID is the story that produced the file, if known.
+	StoryID string
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.StoryID != "" {
+		return fmt.Sprintf("%s (story #%s): %s", p.File, p.StoryID, p.Message)
+	}
+	return fmt.Sprintf("%s: %s", p.File, p.Message)
+}
+
+// Sources maps a path inside the zip to the ID of the story it was generated from.
+type Sources map[string]string
+
+type checker struct {
+	files    map[string]*zip.File
+	sources  Sources
+	problems []Problem
+
+	// element IDs present in each XHTML file, for checking #fragments
+	ids map[string]map[string]bool
+}
+
+func (c *checker) report(file string, format string, args ...interface{}) {
+	c.problems = append(c.problems, Problem{
+		File:    file,
+		StoryID: c.sources[file],
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *checker) read(name string) ([]byte, error) {
+	f, ok := c.files[name]
+	if !ok {
+		return nil, errors.Errorf("file %s not found", name)
+	}
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// resolve turns a reference found in the file 'from' into a path inside the zip and a fragment.
+// ok is false for external references (http:, mailto:, data:).
+func resolve(from, ref string) (target, fragment string, ok bool) {
+	u, err := url.Parse(ref)
+	if err != nil || u.Scheme != "" || u.Host != "" {
+		return "", "", false
+	}
+	if u.Path == "" {
+		return from, u.Fragment, true
+	}
+	return path.Join(path.Dir(from), u.Path), u.Fragment, true
+}
+
+// ValidateFile opens an epub file on disk and validates it.
+func ValidateFile(filename string, sources Sources) ([]Problem, error) {
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "opening %s", filename)
+	}
+	defer r.Close()
+	return Validate(&r.Reader, sources)
+}
+
+// Validate checks the structure of an epub file.
+// An error is only returned if the file could not be examined at all; problems with the book are returned
+// in the slice.
+func Validate(r *zip.Reader, sources Sources) ([]Problem, error) {
+	c := &checker{
+		files:   make(map[string]*zip.File),
+		sources: sources,
+		ids:     make(map[string]map[string]bool),
+	}
+	if c.sources == nil {
+		c.sources = make(Sources)
+	}
+	for _, f := range r.File {
+		c.files[f.Name] = f
+	}
+
+	c.checkMimetype(r)
+
+	opfPath, err := c.findRootfile()
+	if err != nil {
+		c.report("META-INF/container.xml", "%s", err)
+		return c.problems, nil
+	}
+	pkg, err := c.readPackage(opfPath)
+	if err != nil {
+		c.report(opfPath, "%s", err)
+		return c.problems, nil
+	}
+	c.checkPackage(opfPath, pkg)
+
+	sort.SliceStable(c.problems, func(i, j int) bool {
+		return c.problems[i].File < c.problems[j].File
+	})
+	return c.problems, nil
+}
+
+func (c *checker) checkMimetype(r *zip.Reader) {
+	if len(r.File) == 0 {
+		c.report("mimetype", "zip file is empty")
+		return
+	}
+	first := r.File[0]
+	if first.Name != "mimetype" {
+		c.report("mimetype", "must be the first file in the zip, found %s first", first.Name)
+	}
+	f, ok := c.files["mimetype"]
+	if !ok {
+		return
+	}
+	if f.Method != zip.Store {
+		c.report("mimetype", "must be stored without compression")
+	}
+	b, err := c.read("mimetype")
+	if err != nil {
+		c.report("mimetype", "could not read: %s", err)
+	} else if string(b) != "application/epub+zip" {
+		c.report("mimetype", "content is %q, expected application/epub+zip", b)
+	}
+}
+
+func (c *checker) findRootfile() (string, error) {
+	b, err := c.read("META-INF/container.xml")
+	if err != nil {
+		return "", err
+	}
+	var container struct {
+		Rootfiles []struct {
+			FullPath  string `xml:"full-path,attr"`
+			MediaType string `xml:"media-type,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	err = xml.Unmarshal(b, &container)
+	if err != nil {
+		return "", errors.Wrap(err, "parsing container.xml")
+	}
+	for _, v := range container.Rootfiles {
+		if v.MediaType == "application/oebps-package+xml" {
+			return v.FullPath, nil
+		}
+	}
+	return "", errors.Errorf("no OPF rootfile listed")
+}
+
+type opfPackage struct {
+	Version  string `xml:"version,attr"`
+	Manifest []struct {
+		ID         string `xml:"id,attr"`
+		Href       string `xml:"href,attr"`
+		MediaType  string `xml:"media-type,attr"`
+		Properties string `xml:"properties,attr"`
+	} `xml:"manifest>item"`
+	Spine struct {
+		Toc      string `xml:"toc,attr"`
+		Itemrefs []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"itemref"`
+	} `xml:"spine"`
+}
+
+func (c *checker) readPackage(opfPath string) (*opfPackage, error) {
+	b, err := c.read(opfPath)
+	if err != nil {
+		return nil, err
+	}
+	pkg := new(opfPackage)
+	err = xml.Unmarshal(b, pkg)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing OPF")
+	}
+	return pkg, nil
+}
+
+func (c *checker) checkPackage(opfPath string, pkg *opfPackage) {
+	byID := make(map[string]int)
+	inManifest := make(map[string]bool)
+	var xhtmlFiles []string
+	var ncxFile, navFile string
+
+	for i, v := range pkg.Manifest {
+		if _, dupe := byID[v.ID]; dupe {
+			c.report(opfPath, "duplicate manifest id %q", v.ID)
+		}
+		byID[v.ID] = i
+		target, _, ok := resolve(opfPath, v.Href)
+		if !ok {
+			continue
+		}
+		inManifest[target] = true
+		if _, exists := c.files[target]; !exists {
+			c.report(target, "listed in the manifest as %q but missing from the zip", v.ID)
+			continue
+		}
+		switch v.MediaType {
+		case "application/xhtml+xml":
+			xhtmlFiles = append(xhtmlFiles, target)
+		case "application/x-dtbncx+xml":
+			ncxFile = target
+		}
+		for _, prop := range strings.Fields(v.Properties) {
+			if prop == "nav" {
+				navFile = target
+			}
+		}
+	}
+
+	for name := range c.files {
+		if name == "mimetype" || strings.HasPrefix(name, "META-INF/") || name == opfPath || strings.HasSuffix(name, "/") {
+			continue
+		}
+		if !inManifest[name] {
+			c.report(name, "not listed in the manifest")
+		}
+	}
+
+	for _, v := range pkg.Spine.Itemrefs {
+		idx, ok := byID[v.IDRef]
+		if !ok {
+			c.report(opfPath, "spine references unknown manifest id %q", v.IDRef)
+			continue
+		}
+		item := pkg.Manifest[idx]
+		if item.MediaType != "application/xhtml+xml" {
+			target, _, _ := resolve(opfPath, item.Href)
+			c.report(target, "in the spine with media type %s, expected application/xhtml+xml", item.MediaType)
+		}
+	}
+	if len(pkg.Spine.Itemrefs) == 0 {
+		c.report(opfPath, "spine is empty")
+	}
+
+	// Collect IDs first so that links between files can be checked in any order
+	docs := make(map[string]*html.Node)
+	for _, name := range xhtmlFiles {
+		b, err := c.read(name)
+		if err != nil {
+			c.report(name, "could not read: %s", err)
+			continue
+		}
+		c.checkWellFormed(name, b)
+		doc, err := html.Parse(bytes.NewReader(b))
+		if err != nil {
+			c.report(name, "could not parse: %s", err)
+			continue
+		}
+		docs[name] = doc
+		c.ids[name] = collectIDs(doc)
+	}
+
+	for _, name := range xhtmlFiles {
+		doc, ok := docs[name]
+		if !ok {
+			continue
+		}
+		c.checkImages(name, doc, inManifest)
+		if name == navFile {
+			c.checkNavDocument(name, doc)
+		}
+	}
+
+	if ncxFile != "" {
+		c.checkNCX(ncxFile)
+	} else if pkg.Spine.Toc != "" || !strings.HasPrefix(pkg.Version, "3") {
+		c.report(opfPath, "no NCX table of contents in the manifest")
+	}
+	if strings.HasPrefix(pkg.Version, "3") && navFile == "" {
+		c.report(opfPath, "EPUB 3 package has no manifest item with properties=\"nav\"")
+	}
+}
+
+// checkWellFormed reports the first XML syntax error in an XHTML file.
+func (c *checker) checkWellFormed(name string, b []byte) {
+	d := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		_, err := d.Token()
+		if err == io.EOF {
+			return
+		} else if err != nil {
+			c.report(name, "not well-formed XML: %s", err)
+			return
+		}
+	}
+}
+
+func collectIDs(doc *html.Node) map[string]bool {
+	ids := make(map[string]bool)
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			for _, a := range n.Attr {
+				// <a name> is accepted by most readers as a link target
+				if a.Key == "id" || (a.Key == "name" && n.Data == "a") {
+					ids[a.Val] = true
+				}
+			}
+		}
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			walk(ch)
+		}
+	}
+	walk(doc)
+	return ids
+}
+
+func attr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func (c *checker) checkImages(name string, doc *html.Node, inManifest map[string]bool) {
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "img" {
+			src, ok := attr(n, "src")
+			if !ok || src == "" {
+				c.report(name, "<img> without a src")
+			} else if target, _, local := resolve(name, src); !local {
+				c.report(name, "<img src=%q> points outside the book", src)
+			} else if _, exists := c.files[target]; !exists {
+				c.report(name, "<img src=%q> does not resolve to a file in the book", src)
+			} else if !inManifest[target] {
+				c.report(name, "<img src=%q> is not listed in the manifest", src)
+			}
+		}
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			walk(ch)
+		}
+	}
+	walk(doc)
+}
+
+// checkLink reports a TOC link that does not lead to an existing file and element.
+// Problems are attributed to the target file, as that is usually where the missing anchor should be.
+func (c *checker) checkLink(from, ref, label string) {
+	target, fragment, ok := resolve(from, ref)
+	if !ok {
+		c.report(from, "TOC entry %q links outside the book: %s", label, ref)
+		return
+	}
+	if _, exists := c.files[target]; !exists {
+		c.report(from, "TOC entry %q links to missing file %s", label, ref)
+		return
+	}
+	if fragment == "" {
+		return
+	}
+	ids, parsed := c.ids[target]
+	if !parsed {
+		c.report(from, "TOC entry %q links to an anchor in non-XHTML file %s", label, ref)
+		return
+	}
+	if !ids[fragment] {
+		c.problems = append(c.problems, Problem{
+			File:    target,
+			StoryID: c.sources[target],
+			Message: fmt.Sprintf("anchor #%s used by TOC entry %q (in %s) does not exist", fragment, label, from),
+		})
+	}
+}
+
+func (c *checker) checkNavDocument(name string, doc *html.Node) {
+	var walk func(n *html.Node, inNav bool)
+	walk = func(n *html.Node, inNav bool) {
+		if n.Type == html.ElementNode && n.Data == "nav" {
+			inNav = true
+		}
+		if inNav && n.Type == html.ElementNode && n.Data == "a" {
+			if href, ok := attr(n, "href"); ok {
+				c.checkLink(name, href, textContent(n))
+			}
+		}
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			walk(ch, inNav)
+		}
+	}
+	walk(doc, false)
+}
+
+func textContent(n *html.Node) string {
+	var buf bytes.Buffer
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			buf.WriteString(n.Data)
+		}
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			walk(ch)
+		}
+	}
+	walk(n)
+	return strings.TrimSpace(buf.String())
+}
+
+// checkNCX verifies the navMap nesting, the TOC links, and the dtb:depth metadata.
+func (c *checker) checkNCX(name string) {
+	b, err := c.read(name)
+	if err != nil {
+		c.report(name, "could not read: %s", err)
+		return
+	}
+
+	d := xml.NewDecoder(bytes.NewReader(b))
+	var (
+		declaredDepth = -1
+		depth         int
+		maxDepth      int
+		inText        bool
+		label         string
+		stack         []string
+	)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			c.report(name, "navPoint nesting is not balanced: %s", err)
+			return
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name.Local)
+			switch t.Name.Local {
+			case "meta":
+				var metaName, content string
+				for _, a := range t.Attr {
+					if a.Name.Local == "name" {
+						metaName = a.Value
+					} else if a.Name.Local == "content" {
+						content = a.Value
+					}
+				}
+				if metaName == "dtb:depth" {
+					declaredDepth, err = strconv.Atoi(content)
+					if err != nil {
+						c.report(name, "dtb:depth %q is not a number", content)
+					}
+				}
+			case "navPoint":
+				depth++
+				if depth > maxDepth {
+					maxDepth = depth
+				}
+				label = ""
+			case "text":
+				inText = len(stack) >= 2 && stack[len(stack)-2] == "navLabel"
+			case "content":
+				for _, a := range t.Attr {
+					if a.Name.Local == "src" {
+						c.checkLink(name, a.Value, label)
+					}
+				}
+			}
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+			switch t.Name.Local {
+			case "navPoint":
+				depth--
+			case "text":
+				inText = false
+			}
+		case xml.CharData:
+			if inText {
+				label += string(t)
+			}
+		}
+	}
+	if depth != 0 {
+		c.report(name, "navPoint nesting is not balanced: %d left open", depth)
+	}
+	if declaredDepth == -1 {
+		c.report(name, "missing dtb:depth")
+	} else if declaredDepth != maxDepth {
+		c.report(name, "dtb:depth is %d, but the navMap is %d levels deep", declaredDepth, maxDepth)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package validate
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const (
+	testContainer = `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+<rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>`
+	testOPF = `<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="2.0">
+<manifest>
+<item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+<item id="story" href="Text/1-story.html" media-type="application/xhtml+xml"/>
+<item id="img" href="Images/a.png" media-type="image/png"/>
+</manifest>
+<spine toc="ncx"><itemref idref="story"/></spine>
+</package>`
+	testNCX = `<?xml version="1.0"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">
+<head><meta name="dtb:depth" content="2"/></head>
+<navMap>
+<navPoint id="p1"><navLabel><text>Story</text></navLabel><content src="Text/1-story.html"/>
+<navPoint id="p2"><navLabel><text>Chapter</text></navLabel><content src="Text/1-story.html#ch1"/></navPoint>
+</navPoint>
+</navMap>
+</ncx>`
+	testStory = `<?xml version="1.0" encoding="utf-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml"><head><title>Story</title></head>
+<body><h2 id="ch1">Chapter</h2><p><img src="../Images/a.png" alt=""/></p></body></html>`
+)
+
+type testFile struct {
+	name    string
+	content string
+	method  uint16
+}
+
+// testBook returns the files of a small valid book. edit may change them before they are zipped.
+func testBook(edit func(files []testFile) []testFile) *zip.Reader {
+	files := []testFile{
+		{"mimetype", "application/epub+zip", zip.Store},
+		{"META-INF/container.xml", testContainer, zip.Deflate},
+		{"OEBPS/content.opf", testOPF, zip.Deflate},
+		{"OEBPS/toc.ncx", testNCX, zip.Deflate},
+		{"OEBPS/Text/1-story.html", testStory, zip.Deflate},
+		{"OEBPS/Images/a.png", "\x89PNG", zip.Store},
+	}
+	if edit != nil {
+		files = edit(files)
+	}
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, f := range files {
+		fw, err := w.CreateHeader(&zip.FileHeader{Name: f.name, Method: f.method})
+		if err != nil {
+			panic(err)
+		}
+		fw.Write([]byte(f.content))
+	}
+	w.Close()
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// replace returns an edit that replaces old with new in one file.
+func replace(name, old, new string) func([]testFile) []testFile {
+	return func(files []testFile) []testFile {
+		for i := range files {
+			if files[i].name == name {
+				files[i].content = strings.Replace(files[i].content, old, new, 1)
+			}
+		}
+		return files
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func([]testFile) []testFile
+		file    string
+		message string
+	}{
+		{"valid book", nil, "", ""},
+		{"mimetype not first", func(files []testFile) []testFile {
+			files[0], files[1] = files[1], files[0]
+			return files
+		}, "mimetype", "must be the first file"},
+		{"mimetype compressed", func(files []testFile) []testFile {
+			files[0].method = zip.Deflate
+			return files
+		}, "mimetype", "without compression"},
+		{"missing manifest file", func(files []testFile) []testFile {
+			return files[:len(files)-1]
+		}, "OEBPS/Images/a.png", "missing from the zip"},
+		{"file not in manifest", func(files []testFile) []testFile {
+			return append(files, testFile{"OEBPS/Images/b.png", "\x89PNG", zip.Store})
+		}, "OEBPS/Images/b.png", "not listed in the manifest"},
+		{"image in spine", replace("OEBPS/content.opf", `<itemref idref="story"/>`, `<itemref idref="story"/><itemref idref="img"/>`),
+			"OEBPS/Images/a.png", "expected application/xhtml+xml"},
+		{"broken image", replace("OEBPS/Text/1-story.html", "../Images/a.png", "../Images/c.png"),
+			"OEBPS/Text/1-story.html", "does not resolve"},
+		{"remote image", replace("OEBPS/Text/1-story.html", "../Images/a.png", "http://whateleyacademy.net/a.png"),
+			"OEBPS/Text/1-story.html", "points outside the book"},
+		{"missing anchor", replace("OEBPS/Text/1-story.html", `id="ch1"`, `id="ch2"`),
+			"OEBPS/Text/1-story.html", "anchor #ch1 used by TOC entry \"Chapter\""},
+		{"not well-formed", replace("OEBPS/Text/1-story.html", `alt=""/>`, `alt="">`),
+			"OEBPS/Text/1-story.html", "not well-formed XML"},
+		{"unbalanced NCX", replace("OEBPS/toc.ncx", "</navPoint>\n</navPoint>", "</navPoint>"),
+			"OEBPS/toc.ncx", "not balanced"},
+		{"wrong depth", replace("OEBPS/toc.ncx", `content="2"`, `content="1"`),
+			"OEBPS/toc.ncx", "dtb:depth is 1, but the navMap is 2 levels deep"},
+		{"EPUB 3 without nav", replace("OEBPS/content.opf", `version="2.0"`, `version="3.0"`),
+			"OEBPS/content.opf", "no manifest item with properties=\"nav\""},
+	}
+	sources := Sources{"OEBPS/Text/1-story.html": "1"}
+	for _, tt := range tests {
+		problems, err := Validate(testBook(tt.edit), sources)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if tt.message == "" {
+			if len(problems) != 0 {
+				t.Errorf("%s: unexpected problems: %v", tt.name, problems)
+			}
+			continue
+		}
+		found := false
+		for _, p := range problems {
+			if p.File == tt.file && strings.Contains(p.Message, tt.message) {
+				found = true
+				if p.File == "OEBPS/Text/1-story.html" && p.StoryID != "1" {
+					t.Errorf("%s: problem not attributed to the story: %v", tt.name, p)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("%s: expected %s: %s, got %v", tt.name, tt.file, tt.message, problems)
+		}
+	}
+}
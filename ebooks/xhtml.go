@@ -0,0 +1,294 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package ebooks
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+)
+
+const xmlProlog = `<?xml version="1.0" encoding="utf-8" standalone="no"?>`
+
+const (
+	xhtmlNamespace = "http://www.w3.org/1999/xhtml"
+	svgNamespace   = "http://www.w3.org/2000/svg"
+	mathNamespace  = "http://www.w3.org/1998/Math/MathML"
+	xlinkNamespace = "http://www.w3.org/1999/xlink"
+	epubNamespace  = "http://www.idpf.org/2007/ops"
+)
+
+var voidElements = map[string]bool{
+	"area": true, "base": true, "basefont": true, "br": true, "col": true, "embed": true,
+	"frame": true, "hr": true, "img": true, "input": true, "isindex": true, "keygen": true,
+	"link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// Attribute prefixes that can appear in our output.
+// Everything else (o:p, v:shapes, ...) comes from pasted word processor markup and is dropped.
+var attrPrefixes = map[string]string{
+	"xml":   "",
+	"xmlns": "",
+	"xlink": xlinkNamespace,
+	"epub":  epubNamespace,
+}
+
+// RenderXHTML writes the node tree as well-formed XML, suitable for an XHTML content document.
+//
+// Compared to html.Render, void elements are always self-closed, characters that are invisible or
+// not allowed in XML are written as numeric character references (or dropped), and attributes or
+// elements with undeclared namespace prefixes are removed.
+// The XML prolog is not written.
+func RenderXHTML(w io.Writer, n *html.Node) error {
+	bw := bufio.NewWriter(w)
+	x := xhtmlWriter{w: bw}
+	x.render(n, false)
+	return bw.Flush()
+}
+
+type xhtmlWriter struct {
+	w *bufio.Writer
+}
+
+func isXMLNameChar(r rune, first bool) bool {
+	if r == '_' || unicode.IsLetter(r) {
+		return true
+	}
+	if first {
+		return false
+	}
+	return r == '-' || r == '.' || unicode.IsDigit(r)
+}
+
+// isXMLName reports whether s is a valid XML name without a namespace prefix.
+func isXMLName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if !isXMLNameChar(r, i == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func isXMLChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		(r >= 0x20 && r <= 0xD7FF) ||
+		(r >= 0xE000 && r <= 0xFFFD) ||
+		(r >= 0x10000 && r <= 0x10FFFF)
+}
+
+// writeEscaped writes text content or an attribute value.
+func (x *xhtmlWriter) writeEscaped(s string, attr bool) {
+	for _, r := range s {
+		switch {
+		case r == utf8.RuneError:
+			// invalid UTF-8 in the source page
+		case r == '&':
+			x.w.WriteString("&amp;")
+		case r == '<':
+			x.w.WriteString("&lt;")
+		case r == '>':
+			x.w.WriteString("&gt;")
+		case r == '"' && attr:
+			x.w.WriteString("&quot;")
+		case attr && (r == '\n' || r == '\t' || r == '\r'):
+			fmt.Fprintf(x.w, "&#%d;", r)
+		case !isXMLChar(r):
+			// not representable in XML 1.0
+		case r == '\u00a0' || r == '\u00ad' || (r >= '\u2000' && r <= '\u200f') || (r >= '\u2028' && r <= '\u202f') || r == '\u2060' || r == '\ufeff':
+			// invisible characters are written as references so they survive editing
+			fmt.Fprintf(x.w, "&#%d;", r)
+		default:
+			x.w.WriteRune(r)
+		}
+	}
+}
+
+func (x *xhtmlWriter) render(n *html.Node, inForeign bool) {
+	switch n.Type {
+	case html.DocumentNode:
+		x.renderChildren(n, inForeign)
+	case html.DoctypeNode:
+		x.renderDoctype(n)
+	case html.CommentNode:
+		// "--" is not allowed inside XML comments
+		data := strings.Replace(n.Data, "--", "- -", -1)
+		if strings.HasSuffix(data, "-") {
+			data += " "
+		}
+		x.w.WriteString("<!--")
+		x.w.WriteString(data)
+		x.w.WriteString("-->")
+	case html.TextNode:
+		x.writeEscaped(n.Data, false)
+	case html.ElementNode:
+		x.renderElement(n, inForeign)
+	}
+}
+
+func (x *xhtmlWriter) renderChildren(n *html.Node, inForeign bool) {
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		x.render(ch, inForeign)
+	}
+}
+
+func (x *xhtmlWriter) renderDoctype(n *html.Node) {
+	var public, system string
+	for _, a := range n.Attr {
+		switch a.Key {
+		case "public":
+			public = a.Val
+		case "system":
+			system = a.Val
+		}
+	}
+	x.w.WriteString("<!DOCTYPE ")
+	x.w.WriteString(n.Data)
+	if public != "" {
+		fmt.Fprintf(x.w, ` PUBLIC "%s"`, public)
+		if system != "" {
+			fmt.Fprintf(x.w, ` "%s"`, system)
+		}
+	} else if system != "" {
+		fmt.Fprintf(x.w, ` SYSTEM "%s"`, system)
+	}
+	x.w.WriteString(">\n")
+}
+
+func (x *xhtmlWriter) renderElement(n *html.Node, inForeign bool) {
+	name := n.Data
+	if !inForeign {
+		name = strings.ToLower(name)
+	}
+	if !isXMLName(name) {
+		// Unknown prefixed elements such as <o:p>, or garbage from broken markup
+		x.renderChildren(n, inForeign)
+		return
+	}
+
+	x.w.WriteByte('<')
+	x.w.WriteString(name)
+
+	seen := make(map[string]bool)
+	needNS := ""
+	switch {
+	case n.Namespace == "svg" && !inForeign:
+		needNS = svgNamespace
+	case n.Namespace == "math" && !inForeign:
+		needNS = mathNamespace
+	case name == "html" && n.Namespace == "":
+		needNS = xhtmlNamespace
+	}
+	usedPrefixes := make(map[string]bool)
+	for _, a := range n.Attr {
+		key := a.Key
+		if a.Namespace != "" {
+			key = a.Namespace + ":" + a.Key
+		} else if !inForeign && n.Namespace == "" {
+			// SVG and MathML attribute names are case-sensitive (viewBox)
+			key = strings.ToLower(key)
+		}
+		if idx := strings.IndexByte(key, ':'); idx != -1 {
+			prefix := key[:idx]
+			if _, ok := attrPrefixes[prefix]; !ok || !isXMLName(key[idx+1:]) {
+				continue
+			}
+			if ns := attrPrefixes[prefix]; ns != "" {
+				usedPrefixes[prefix] = true
+			}
+		} else if !isXMLName(key) {
+			continue
+		}
+		if key == "xmlns" {
+			needNS = ""
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		x.w.WriteByte(' ')
+		x.w.WriteString(key)
+		x.w.WriteString(`="`)
+		x.writeEscaped(a.Val, true)
+		x.w.WriteByte('"')
+	}
+	if needNS != "" {
+		fmt.Fprintf(x.w, ` xmlns="%s"`, needNS)
+	}
+	// sorted, so that the output is stable
+	for _, prefix := range []string{"epub", "xlink"} {
+		if usedPrefixes[prefix] && !seen["xmlns:"+prefix] {
+			fmt.Fprintf(x.w, ` xmlns:%s="%s"`, prefix, attrPrefixes[prefix])
+		}
+	}
+
+	childForeign := inForeign || n.Namespace == "svg" || n.Namespace == "math"
+	if (voidElements[name] && !childForeign) || (childForeign && n.FirstChild == nil) {
+		// Void elements never have content, anything the parser attached is dropped
+		x.w.WriteString("/>")
+		return
+	}
+	x.w.WriteByte('>')
+	x.renderChildren(n, childForeign)
+	x.w.WriteString("</")
+	x.w.WriteString(name)
+	x.w.WriteByte('>')
+}
+
+// renderPage executes an HTML page template and parses the result into a node tree.
+func renderPage(tmpl *template.Template, data interface{}) (*html.Node, error) {
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, data)
+	if err != nil {
+		return nil, err
+	}
+	return html.Parse(&buf)
+}
+
+// html5Doctype replaces the XHTML 1.1 DOCTYPE of the page templates with the HTML5 one that EPUB 3
+// content documents use.
+func html5Doctype(doc *html.Node) {
+	for n := doc.FirstChild; n != nil; n = n.NextSibling {
+		if n.Type == html.DoctypeNode {
+			n.Data = "html"
+			n.Attr = nil
+		}
+	}
+}
+
+// writeXHTMLFile writes a parsed page into the book as an XHTML file, and returns the size of the
+// file. EPUB 3 pages get the HTML5 DOCTYPE.
+func writeXHTMLFile(fs fileCreator, filename string, doc *html.Node, epub3 bool) (int, error) {
+	if epub3 {
+		html5Doctype(doc)
+	}
+	file, err := fs.Create(filename)
+	if err != nil {
+		return 0, errors.Wrapf(err, "creating target file for %s", filename)
+	}
+	var size countingWriter
+	w := bufio.NewWriter(io.MultiWriter(file, &size))
+	w.WriteString(xmlProlog)
+	w.WriteByte('\n')
+	err = RenderXHTML(w, doc)
+	if err != nil {
+		return 0, errors.Wrapf(err, "writing target file for %s", filename)
+	}
+	err = w.Flush()
+	if err != nil {
+		return 0, errors.Wrapf(err, "writing target file for %s", filename)
+	}
+	return int(size), nil
+}
@@ -0,0 +1,108 @@
+// Copyright © Kane York 2016.
+// Please see COPYRIGHT.md and LICENSE-CODE.txt.
+
+package ebooks
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// renderBody parses an HTML fragment as the body of a page and returns the XHTML of the body.
+func renderBody(t *testing.T, in string) string {
+	doc, err := html.Parse(strings.NewReader("<html><head><title>x</title></head><body>" + in + "</body></html>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	err = RenderXHTML(&buf, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	d := xml.NewDecoder(strings.NewReader(out))
+	for {
+		_, err := d.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Errorf("%q: output is not well-formed XML: %v\n%s", in, err, out)
+			break
+		}
+	}
+
+	i, j := strings.Index(out, "<body>"), strings.LastIndex(out, "</body>")
+	if i == -1 || j == -1 {
+		t.Fatalf("%q: no body in output:\n%s", in, out)
+	}
+	return out[i+len("<body>") : j]
+}
+
+func TestRenderXHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		out  string
+	}{
+		{"void elements", `a<br>b<hr><img src="x.png" alt="">`, `a<br/>b<hr/><img src="x.png" alt=""/>`},
+		{"void element content dropped", `<p>a<br>b</br>c</p>`, `<p>a<br/>b<br/>c</p>`},
+		{"named entities", `a&nbsp;b &amp; &lt;c&gt; &mdash;`, `a&#160;b &amp; &lt;c&gt; —`},
+		{"invisible characters", "soft\u00adhyphen zero\u200bwidth", `soft&#173;hyphen zero&#8203;width`},
+		{"control characters dropped", "a\x01b\x0bc", `abc`},
+		{"attribute escaping", `<p title="a &quot;b&quot; <c> & d">x</p>`, `<p title="a &quot;b&quot; &lt;c&gt; &amp; d">x</p>`},
+		{"attribute newline", "<p title=\"a\nb\">x</p>", `<p title="a&#10;b">x</p>`},
+		{"uppercase tags and attributes", `<P CLASS="x">a</P>`, `<p class="x">a</p>`},
+		{"unquoted attribute", `<font size=3>a</font>`, `<font size="3">a</font>`},
+		{"duplicate attribute", `<p class="a" class="b">x</p>`, `<p class="a">x</p>`},
+		{"word processor namespaces", `<p class="MsoNormal" o:spid="1">a<o:p></o:p></p>`, `<p class="MsoNormal">a</p>`},
+		{"invalid attribute name", `<p "x"="1" 1a="2">a</p>`, `<p>a</p>`},
+		{"comment with dashes", `<!-- a -- b -->`, `<!-- a - - b -->`},
+		{"comment ending in a dash", `<!--a-->`, `<!--a-->`},
+		{"svg namespace", `<svg viewBox="0 0 1 1"><image xlink:href="a.png"></image></svg>`,
+			`<svg viewBox="0 0 1 1" xmlns="http://www.w3.org/2000/svg"><image xlink:href="a.png" xmlns:xlink="http://www.w3.org/1999/xlink"/></svg>`},
+		{"epub namespace", `<a epub:type="noteref" href="#n1">1</a>`, `<a epub:type="noteref" href="#n1" xmlns:epub="http://www.idpf.org/2007/ops">1</a>`},
+		{"nbsp paragraph", `<p>&nbsp;</p>`, `<p>&#160;</p>`},
+	}
+	for _, tt := range tests {
+		out := renderBody(t, tt.in)
+		if out != tt.out {
+			t.Errorf("%s:\n  in:  %s\n  got: %s\n  exp: %s", tt.name, tt.in, out, tt.out)
+		}
+	}
+}
+
+func TestRenderXHTMLDocument(t *testing.T) {
+	in := `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.1//EN" "http://www.w3.org/TR/xhtml11/DTD/xhtml11.dtd">
+<html><head><meta charset="utf-8"><title>T</title></head><body><p>x</p></body></html>`
+	for _, epub3 := range []bool{false, true} {
+		doc, err := html.Parse(strings.NewReader(in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if epub3 {
+			html5Doctype(doc)
+		}
+		var buf bytes.Buffer
+		err = RenderXHTML(&buf, doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		doctype := `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.1//EN" "http://www.w3.org/TR/xhtml11/DTD/xhtml11.dtd">`
+		if epub3 {
+			doctype = `<!DOCTYPE html>`
+		}
+		if !strings.HasPrefix(out, doctype+"\n") {
+			t.Errorf("epub3=%v: wrong DOCTYPE:\n%s", epub3, out)
+		}
+		if !strings.Contains(out, `<html xmlns="http://www.w3.org/1999/xhtml">`) || !strings.Contains(out, `<meta charset="utf-8"/>`) {
+			t.Errorf("epub3=%v: wrong head:\n%s", epub3, out)
+		}
+	}
+}